@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/jfbramlett/go-aop/pkg/stackutils"
 	"regexp"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -13,7 +15,9 @@ const (
 	UnknownMethod     = "Unknown"
 	// CallsBackToMethod indicates the number of steps back the call stack to use
 	CallsBackToMethod = 2
-	// Method is a token used to store the calling method in the context
+	// Method is a token used to store the calling method in the context. It is only set for methods with matching
+	// advice, so in an unadvised method (and anything it calls) it holds the name of the nearest enclosing advised
+	// method rather than the current one. Use AspectFromContext to check the method is advised
 	Method = "method"
 )
 
@@ -21,6 +25,21 @@ type contextKey struct{}
 
 var aopCtxKey = contextKey{}
 
+type chainContextKey struct{}
+
+var aopChainCtxKey = chainContextKey{}
+
+type argsContextKey struct{}
+
+var aopArgsCtxKey = argsContextKey{}
+
+// capturedArgs holds the arguments captured by ContextWithArgs until they are claimed by the Before of the method
+// they were captured for, so methods it calls don't report them as their own
+type capturedArgs struct {
+	args    []interface{}
+	claimed atomic.Bool
+}
+
 
 // Advice is the interface implemented to handle a cross-cutting concern
 type Advice interface {
@@ -28,6 +47,16 @@ type Advice interface {
 	After(ctx context.Context, err error)
 }
 
+// noopAdvice does nothing, used in place of an advice that couldn't be created so advised methods still run
+type noopAdvice struct{}
+
+func (noopAdvice) Before(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (noopAdvice) After(ctx context.Context, err error) {
+}
+
 // AspectMgr is responsible for handling identifying and running our cross cutting concern
 type AspectMgr interface {
 	GetServiceName() string
@@ -42,6 +71,8 @@ var globalAspectMgr AspectMgr
 type Aspect struct {
 	MethodName        	string
 	joinPoints         	[]joinPoint
+	// methodValue is MethodName pre-boxed so handing it out from the context doesn't allocate
+	methodValue			interface{}
 }
 
 // Pointcut defines how we determine if a given advice is relevant to the specified method
@@ -58,20 +89,68 @@ func (r *regexPointcut) Matches(method string) bool {
 	return err == nil && matches
 }
 
+// String returns the regex pattern backing the pointcut
+func (r *regexPointcut) String() string {
+	return r.pattern
+}
+
 // NewRegexPointcut returns a new Pointcut that uses regex pattern matching
 func NewRegexPointcut(pattern string) Pointcut {
 	return &regexPointcut{pattern: pattern}
 }
 
+// aspectChain links the aspect for the current invocation to the aspect of the invocation that called it
+type aspectChain struct {
+	aspect *Aspect
+	parent *aspectChain
+	// callerPC is the call site of the method that called the advised method (0 if not known)
+	callerPC uintptr
+	mgr      *aspectMgr
+	// args are the arguments captured for this invocation (nil if none were)
+	args []interface{}
+}
+
+// aspectContext carries the aspect (and call chain) for an invocation, answering for all of our context keys
+// so entering an advised method costs a single allocation rather than one per key
+type aspectContext struct {
+	context.Context
+	chain aspectChain
+}
+
+func (c *aspectContext) Value(key interface{}) interface{} {
+	switch key {
+	case aopCtxKey:
+		return c.chain.aspect
+	case aopChainCtxKey:
+		return &c.chain
+	case Method:
+		return c.chain.aspect.methodValue
+	}
+	return c.Context.Value(key)
+}
+
 type joinPoint struct {
 	pointcut 		Pointcut
 	advice        	Advice
 }
 
+// resolution caches the aspect resolved for each method, and for each call site by program counter. It is
+// never modified once published, misses copy it and publish a new one
+type resolution struct {
+	byName  map[string]*Aspect
+	byPC    map[uintptr]*Aspect
+	callers map[uintptr]string
+}
+
+func newResolution() *resolution {
+	return &resolution{byName: make(map[string]*Aspect), byPC: make(map[uintptr]*Aspect), callers: make(map[uintptr]string)}
+}
+
 type aspectMgr struct {
+	mux         sync.Mutex
 	joinPoints  []joinPoint
 	serviceName string
-	methodMap   map[string]*Aspect
+	resolved    atomic.Value
 }
 
 // GetServiceName gets the name of the service we are running in
@@ -81,40 +160,131 @@ func (a *aspectMgr) GetServiceName() string {
 
 // RegisterJoinPoint registers a new advice for a given pointcut. The pointcut is a regex pattern used to match against a method name
 func (a *aspectMgr) RegisterJoinPoint(pointcut Pointcut, advice Advice) {
-	jp := joinPoint{pointcut: pointcut, advice: advice}
-	a.joinPoints = append(a.joinPoints, jp)
+	a.mux.Lock()
+	defer a.mux.Unlock()
 
-	for method, aspect := range a.methodMap {
-		if jp.pointcut.Matches(method) {
-			aspect.joinPoints = append(aspect.joinPoints, jp)
-		}
-	}
+	a.joinPoints = append(a.joinPoints, joinPoint{pointcut: pointcut, advice: advice})
+
+	// drop everything resolved so far so the next call to each method picks up the new join point
+	a.resolved.Store(newResolution())
 }
 
 // Before loops over all of the registered joinpoints and executes the Before advice for those whose pointcuts match
 func (a *aspectMgr) Before(ctx context.Context, method string) context.Context {
-	ac, found := a.methodMap[method]
-	if !found {
-		ac = &Aspect{joinPoints: make([]joinPoint, 0), MethodName: method}
-		for _, k := range a.joinPoints {
-			if k.pointcut.Matches(method) {
-				ac.joinPoints = append(ac.joinPoints, k)
-			}
-		}
-		a.methodMap[method] = ac
+	ctx, _ = a.runBefore(ctx, a.aspectForMethod(method), 0)
+	return ctx
+}
+
+// beforePC is the same as Before but resolves the method from the program counter of the call site, callerPC is
+// the call site of the method's caller (0 if not known). advised is false if the method has no join points
+func (a *aspectMgr) beforePC(ctx context.Context, pc uintptr, callerPC uintptr) (_ context.Context, advised bool) {
+	return a.runBefore(ctx, a.aspectForPC(pc), callerPC)
+}
+
+func (a *aspectMgr) runBefore(ctx context.Context, ac *Aspect, callerPC uintptr) (_ context.Context, advised bool) {
+	// claimed even if the method isn't advised so an advised method it calls doesn't take them
+	args := claimArgs(ctx)
+	if len(ac.joinPoints) == 0 {
+		return ctx, false
+	}
+
+	ctx = &aspectContext{Context: ctx, chain: aspectChain{aspect: ac, parent: chainFromContext(ctx), callerPC: callerPC, mgr: a,
+		args: args}}
+	for _, r := range ac.joinPoints {
+		ctx = r.advice.Before(ctx)
+	}
+
+	return ctx, true
+}
+
+func (a *aspectMgr) resolution() *resolution {
+	return a.resolved.Load().(*resolution)
+}
+
+// aspectForMethod gets the aspect for the given method, building it from the matching join points the first time
+// the method is seen
+func (a *aspectMgr) aspectForMethod(method string) *Aspect {
+	if ac, found := a.resolution().byName[method]; found {
+		return ac
 	}
 
-	beforeCtx := context.WithValue(ctx, Method, method)
+	a.mux.Lock()
+	defer a.mux.Unlock()
 
-	if len(ac.joinPoints) > 0 {
-		ctx = context.WithValue(beforeCtx, aopCtxKey, ac)
+	current := a.resolution()
+	if ac, found := current.byName[method]; found {
+		return ac
+	}
 
-		for _, r := range ac.joinPoints {
-			ctx = r.advice.Before(ctx)
+	ac := &Aspect{joinPoints: make([]joinPoint, 0), MethodName: method, methodValue: method}
+	for _, k := range a.joinPoints {
+		if k.pointcut.Matches(method) {
+			ac.joinPoints = append(ac.joinPoints, k)
 		}
 	}
 
-	return ctx
+	byName := make(map[string]*Aspect, len(current.byName)+1)
+	for k, v := range current.byName {
+		byName[k] = v
+	}
+	byName[method] = ac
+	a.resolved.Store(&resolution{byName: byName, byPC: current.byPC, callers: current.callers})
+
+	return ac
+}
+
+// aspectForPC gets the aspect for the method at the given call site, the method name is only looked up the first
+// time a call site is seen
+func (a *aspectMgr) aspectForPC(pc uintptr) *Aspect {
+	if ac, found := a.resolution().byPC[pc]; found {
+		return ac
+	}
+
+	return a.cachePC(pc, a.aspectForMethod(stackutils.EnclosingMethodName(stackutils.FuncNameForPC(pc))))
+}
+
+// cachePC caches the aspect resolved for the call site, unless join points were registered since it was resolved
+func (a *aspectMgr) cachePC(pc uintptr, ac *Aspect) *Aspect {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	current := a.resolution()
+	if current.byName[ac.MethodName] != ac {
+		// a join point was registered while we were resolving, don't cache the stale aspect
+		return ac
+	}
+
+	byPC := make(map[uintptr]*Aspect, len(current.byPC)+1)
+	for k, v := range current.byPC {
+		byPC[k] = v
+	}
+	byPC[pc] = ac
+	a.resolved.Store(&resolution{byName: current.byName, byPC: byPC, callers: current.callers})
+
+	return ac
+}
+
+// callerName gets the name of the method at the given call site, the name is only looked up the first time a
+// call site is seen
+func (a *aspectMgr) callerName(pc uintptr) string {
+	if name, found := a.resolution().callers[pc]; found {
+		return name
+	}
+
+	name := stackutils.FuncNameForPC(pc)
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	current := a.resolution()
+	callers := make(map[uintptr]string, len(current.callers)+1)
+	for k, v := range current.callers {
+		callers[k] = v
+	}
+	callers[pc] = name
+	a.resolved.Store(&resolution{byName: current.byName, byPC: current.byPC, callers: callers})
+
+	return name
 }
 
 func (a *aspectMgr) After(ctx context.Context, err error) {
@@ -126,12 +296,23 @@ func (a *aspectMgr) After(ctx context.Context, err error) {
 	}
 }
 
+// NewAspectMgr creates a new, empty, AspectMgr for the given service
+func NewAspectMgr(service string) AspectMgr {
+	mgr := &aspectMgr{serviceName: service, joinPoints: make([]joinPoint, 0)}
+	mgr.resolved.Store(newResolution())
+	return mgr
+}
+
 // InitAOP initializes our aspects
 func InitAOP(service string) {
-	globalAspectMgr = &aspectMgr{serviceName: service, 
-		joinPoints: make([]joinPoint, 0),
-		methodMap: make(map[string]*Aspect),
-	}
+	globalAspectMgr = NewAspectMgr(service)
+}
+
+// SetAspectMgr replaces the global AspectMgr returning the one previously in place (which may be nil)
+func SetAspectMgr(mgr AspectMgr) AspectMgr {
+	previous := globalAspectMgr
+	globalAspectMgr = mgr
+	return previous
 }
 
 // GetServiceName gets the name of the service
@@ -150,14 +331,46 @@ func RegisterJoinPoint(pointcut Pointcut, advice Advice) {
 	}
 }
 
-// Before is the function invoked at the start of a method to execute any registered joinPoints
+// Before is the function invoked at the start of a method to execute any registered joinPoints, the method is
+// identified from the call stack (calls made from within a closure are attributed to the enclosing method)
 func Before(ctx context.Context) context.Context {
+	ctx, _ = before(ctx, 1)
+	return ctx
+}
+
+// BeforeSkip is the same as Before but skips the given number of additional frames when identifying the method,
+// used when Before is called from a helper rather than the method itself
+func BeforeSkip(ctx context.Context, skip int) context.Context {
+	ctx, _ = before(ctx, 1+skip)
+	return ctx
+}
+
+// BeforeNamed is the same as Before but uses the given method name rather than looking it up from the call
+// stack, for hot paths that want to avoid the lookup
+func BeforeNamed(ctx context.Context, method string) context.Context {
 	if globalAspectMgr != nil {
-		return globalAspectMgr.Before(ctx, stackutils.GetCallingMethodName())
+		return globalAspectMgr.Before(ctx, method)
 	}
 	return ctx
 }
 
+// before runs the before advice for the method skip frames above the caller of before, our own manager resolves
+// the method by call site so the name is only looked up the first time through. advised is false if no advice
+// ran, other managers are always treated as having run advice
+func before(ctx context.Context, skip int) (_ context.Context, advised bool) {
+	switch mgr := globalAspectMgr.(type) {
+	case nil:
+		return ctx, false
+	case *aspectMgr:
+		// grab the call site of the method and of its caller in one go, the caller is only resolved if asked for
+		var pcs [2]uintptr
+		stackutils.CallerPCs(skip+1, pcs[:])
+		return mgr.beforePC(ctx, pcs[0], pcs[1])
+	default:
+		return mgr.Before(ctx, stackutils.EnclosingMethodName(stackutils.CallerName(skip+1))), true
+	}
+}
+
 // After is a global func used to execute our aspect
 func After(ctx context.Context, err error) {
 	if globalAspectMgr != nil {
@@ -175,4 +388,74 @@ func AspectFromContext(ctx context.Context) *Aspect {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// AspectChainFromContext gets the method names of the aspects in the current call chain, outermost first
+func AspectChainFromContext(ctx context.Context) []string {
+	methods := make([]string, 0)
+	for chain := chainFromContext(ctx); chain != nil; chain = chain.parent {
+		methods = append([]string{chain.aspect.MethodName}, methods...)
+	}
+	return methods
+}
+
+// CallingMethodFromContext gets the fully qualified name of the method that called the current advised method.
+// This is looked up from the call site captured by Before (once per call site), when that isn't available (such
+// as with BeforeNamed) the nearest advised method up the call chain is used
+func CallingMethodFromContext(ctx context.Context) string {
+	chain := chainFromContext(ctx)
+	switch {
+	case chain == nil:
+		return UnknownMethod
+	case chain.callerPC != 0 && chain.mgr != nil:
+		return chain.mgr.callerName(chain.callerPC)
+	case chain.parent != nil:
+		return chain.parent.aspect.MethodName
+	}
+	return UnknownMethod
+}
+
+func chainFromContext(ctx context.Context) *aspectChain {
+	ctxVal := ctx.Value(aopChainCtxKey)
+	if ctxVal != nil {
+		if chain, ok := ctxVal.(*aspectChain); ok {
+			return chain
+		}
+	}
+
+	return nil
+}
+
+// ContextWithArgs captures the arguments of the method being invoked so advices can report on them, it should be
+// called on the context handed to Before. The arguments belong to that invocation, advised methods it calls don't
+// see them
+func ContextWithArgs(ctx context.Context, args ...interface{}) context.Context {
+	return context.WithValue(ctx, aopArgsCtxKey, &capturedArgs{args: args})
+}
+
+// ArgsFromContext gets the arguments captured for the current method (or nil if none were captured)
+func ArgsFromContext(ctx context.Context) []interface{} {
+	captured, _ := ctx.Value(aopArgsCtxKey).(*capturedArgs)
+	if captured != nil && !captured.claimed.Load() {
+		// captured for a method whose Before hasn't run yet
+		return captured.args
+	}
+	if chain := chainFromContext(ctx); chain != nil {
+		return chain.args
+	}
+	if captured != nil {
+		return captured.args
+	}
+
+	return nil
+}
+
+// claimArgs gets the arguments captured for the method being entered, arguments already claimed by an enclosing
+// invocation are not returned
+func claimArgs(ctx context.Context) []interface{} {
+	captured, _ := ctx.Value(aopArgsCtxKey).(*capturedArgs)
+	if captured == nil || !captured.claimed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return captured.args
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"reflect"
 	"testing"
 )
 
@@ -106,6 +107,125 @@ func TestAspectFromContext(t *testing.T) {
 
 }
 
+func TestBeforeVariants(t *testing.T) {
+	t.Run("before_named", func(t *testing.T) {
+		// given
+		collector := &aspectCollector{methodCalls: make([]methodCall, 0)}
+		InitAOP("beforeNamed")
+		RegisterJoinPoint(NewRegexPointcut("^hotPath$"), &countingAspect{collector: collector})
+
+		// when
+		ctx := BeforeNamed(context.Background(), "hotPath")
+		After(ctx, nil)
+
+		// then
+		assert.Equal(t, []methodCall{{BeforeFrame, "hotPath", CountAdvice}, {AfterFrame, "hotPath", CountAdvice}},
+			collector.methodCalls)
+	})
+
+	t.Run("before_in_closure", func(t *testing.T) {
+		// given
+		collector := &aspectCollector{methodCalls: make([]methodCall, 0)}
+		InitAOP("beforeClosure")
+		RegisterJoinPoint(NewRegexPointcut(".*Closure\\d$"), &countingAspect{collector: collector})
+
+		// when
+		st := sampleStruct{collector: collector}
+		_, _ = st.Closure1("arg1", 1)
+
+		// then
+		assert.Equal(t, []methodCall{{BeforeFrame, "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Closure1", CountAdvice},
+			{MethodFrame, "Closure1", MethodAdvice},
+			{AfterFrame, "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Closure1", CountAdvice},
+		}, collector.methodCalls)
+	})
+
+	t.Run("before_skip_helper", func(t *testing.T) {
+		// given
+		collector := &aspectCollector{methodCalls: make([]methodCall, 0)}
+		InitAOP("beforeSkip")
+		RegisterJoinPoint(NewRegexPointcut(".*Helped\\d$"), &countingAspect{collector: collector})
+
+		// when
+		st := sampleStruct{collector: collector}
+		_, _ = st.Helped1("arg1", 1)
+
+		// then
+		assert.Equal(t, []methodCall{{BeforeFrame, "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Helped1", CountAdvice},
+			{MethodFrame, "Helped1", MethodAdvice},
+			{AfterFrame, "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Helped1", CountAdvice},
+		}, collector.methodCalls)
+	})
+}
+func TestAspectResolution(t *testing.T) {
+	t.Run("reregistered_while_resolving", func(t *testing.T) {
+		// given
+		collector := &aspectCollector{methodCalls: make([]methodCall, 0)}
+		InitAOP("reregistered")
+		mgr := globalAspectMgr.(*aspectMgr)
+		pc := reflect.ValueOf((*sampleStruct).Method1).Pointer()
+		method := "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1"
+
+		// resolved before the join point is registered, and again by another call after it is
+		stale := mgr.aspectForMethod(method)
+		mgr.RegisterJoinPoint(NewRegexPointcut(".*Method1$"), &countingAspect{collector: collector})
+		fresh := mgr.aspectForMethod(method)
+
+		// when
+		resolved := mgr.cachePC(pc, stale)
+
+		// then
+		assert.Equal(t, stale, resolved)
+		_, cached := mgr.resolution().byPC[pc]
+		assert.False(t, cached)
+		assert.Same(t, fresh, mgr.aspectForPC(pc))
+		assert.Equal(t, 1, len(mgr.aspectForPC(pc).joinPoints))
+	})
+}
+
+func TestCallingMethodFromContext(t *testing.T) {
+	t.Run("from_call_site", func(t *testing.T) {
+		// given
+		InitAOP("callingMethodCallSite")
+		capture := &callingMethodAspect{}
+		RegisterJoinPoint(NewRegexPointcut(".*Method1$"), capture)
+
+		// when
+		st := sampleStruct{collector: &aspectCollector{}}
+		_, _ = st.Method1("arg1", 1)
+
+		// then
+		assert.Equal(t, "github.com/jfbramlett/go-aop/pkg/aop.TestCallingMethodFromContext.func1", capture.callingMethod)
+	})
+
+	t.Run("from_parent_aspect", func(t *testing.T) {
+		// given
+		InitAOP("callingMethodParent")
+		capture := &callingMethodAspect{}
+		RegisterJoinPoint(NewRegexPointcut("^(parent|child)$"), capture)
+
+		// when
+		parentCtx := BeforeNamed(context.Background(), "parent")
+		childCtx := BeforeNamed(parentCtx, "child")
+
+		// then
+		assert.Equal(t, "parent", CallingMethodFromContext(childCtx))
+		assert.Equal(t, UnknownMethod, CallingMethodFromContext(parentCtx))
+		assert.Equal(t, UnknownMethod, CallingMethodFromContext(context.Background()))
+	})
+}
+
+type callingMethodAspect struct {
+	callingMethod string
+}
+
+func (c *callingMethodAspect) Before(ctx context.Context) context.Context {
+	c.callingMethod = CallingMethodFromContext(ctx)
+	return ctx
+}
+
+func (c *callingMethodAspect) After(ctx context.Context, err error) {
+}
 
 type sampleStruct struct {
 	collector		*aspectCollector
@@ -147,6 +267,31 @@ func (s *sampleStruct) Special(arg1 string, arg2 int) (result string, err error)
 	return "success", nil
 }
 
+func (s *sampleStruct) Closure1(arg1 string, arg2 int) (result string, err error) {
+	func() {
+		ctx := Before(context.Background())
+		defer func() {After(ctx, err)}()
+
+		s.collector.Collect(MethodFrame, "Closure1", MethodAdvice)
+	}()
+
+	return "success", nil
+}
+
+func (s *sampleStruct) Helped1(arg1 string, arg2 int) (result string, err error) {
+	ctx := s.startAspect(context.Background())
+	defer func() {After(ctx, err)}()
+
+	s.collector.Collect(MethodFrame, "Helped1", MethodAdvice)
+
+	return "success", nil
+}
+
+// startAspect is a helper wrapping Before so needs to skip its own frame
+func (s *sampleStruct) startAspect(ctx context.Context) context.Context {
+	return BeforeSkip(ctx, 1)
+}
+
 func (s *sampleStruct) privateMethod1(arg1 string, arg2 int) (result string, err error) {
 	ctx := Before(context.Background())
 	defer func() {After(ctx, err)}()
@@ -0,0 +1,166 @@
+// Package aoptest provides helpers for unit testing custom advices and pointcuts
+package aoptest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/aop"
+)
+
+const (
+	// BeforeFrame marks an invocation recorded from an advice's Before
+	BeforeFrame = "before"
+	// AfterFrame marks an invocation recorded from an advice's After
+	AfterFrame = "after"
+)
+
+// NewManager installs a new, isolated, AspectMgr for the duration of the test restoring the previous one when
+// the test completes
+func NewManager(t testing.TB, service string) aop.AspectMgr {
+	mgr := aop.NewAspectMgr(service)
+	previous := aop.SetAspectMgr(mgr)
+	t.Cleanup(func() { aop.SetAspectMgr(previous) })
+	return mgr
+}
+
+// Invocation is a single call into a RecordingAdvice
+type Invocation struct {
+	Frame  string
+	Method string
+	Err    error
+}
+
+// NewRecordingAdvice creates a new advice that records each Before and After it sees
+func NewRecordingAdvice() *RecordingAdvice {
+	return &RecordingAdvice{invocations: make([]Invocation, 0)}
+}
+
+// RecordingAdvice is an advice that records every Before and After call so tests can assert on them
+type RecordingAdvice struct {
+	mux         sync.Mutex
+	invocations []Invocation
+}
+
+func (r *RecordingAdvice) Before(ctx context.Context) context.Context {
+	r.record(ctx, BeforeFrame, nil)
+	return ctx
+}
+
+func (r *RecordingAdvice) After(ctx context.Context, err error) {
+	r.record(ctx, AfterFrame, err)
+}
+
+// Invocations gets a copy of the invocations recorded so far
+func (r *RecordingAdvice) Invocations() []Invocation {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]Invocation{}, r.invocations...)
+}
+
+// Methods gets the methods the advice was invoked for, in the order they were called
+func (r *RecordingAdvice) Methods() []string {
+	methods := make([]string, 0)
+	for _, i := range r.Invocations() {
+		if i.Frame == BeforeFrame {
+			methods = append(methods, i.Method)
+		}
+	}
+	return methods
+}
+
+// Reset clears the recorded invocations
+func (r *RecordingAdvice) Reset() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.invocations = make([]Invocation, 0)
+}
+
+// AssertInvoked asserts the advice was run for the given method, the method can be the fully qualified name or any
+// trailing part of it (e.g. "Method1" or "(*myStruct).Method1")
+func (r *RecordingAdvice) AssertInvoked(t testing.TB, method string) bool {
+	t.Helper()
+	for _, m := range r.Methods() {
+		if methodMatches(m, method) {
+			return true
+		}
+	}
+	t.Errorf("expected advice to be invoked for %s, invoked for %v", method, r.Methods())
+	return false
+}
+
+// AssertNotInvoked asserts the advice was never run for the given method
+func (r *RecordingAdvice) AssertNotInvoked(t testing.TB, method string) bool {
+	t.Helper()
+	for _, m := range r.Methods() {
+		if methodMatches(m, method) {
+			t.Errorf("expected advice not to be invoked for %s", method)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertOrder asserts the advice was run for exactly the given methods in the given order
+func (r *RecordingAdvice) AssertOrder(t testing.TB, methods ...string) bool {
+	t.Helper()
+	actual := r.Methods()
+	if len(actual) != len(methods) {
+		t.Errorf("expected advice to be invoked for %v, invoked for %v", methods, actual)
+		return false
+	}
+	for i := range methods {
+		if !methodMatches(actual[i], methods[i]) {
+			t.Errorf("expected advice to be invoked for %v, invoked for %v", methods, actual)
+			return false
+		}
+	}
+	return true
+}
+
+func (r *RecordingAdvice) record(ctx context.Context, frame string, err error) {
+	method := aop.UnknownMethod
+	if aspect := aop.AspectFromContext(ctx); aspect != nil {
+		method = aspect.MethodName
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.invocations = append(r.invocations, Invocation{Frame: frame, Method: method, Err: err})
+}
+
+func methodMatches(fullMethod string, method string) bool {
+	return fullMethod == method || strings.HasSuffix(fullMethod, "."+method)
+}
+
+// NewFakeClock creates a clock fixed at the given time and installs it for the timing advices for the duration of
+// the test
+func NewFakeClock(t testing.TB, now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	previous := aop.SetClock(c)
+	t.Cleanup(func() { aop.SetClock(previous) })
+	return c
+}
+
+// FakeClock is a clock that only moves when told to
+type FakeClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+// Now gets the current time of the clock
+func (f *FakeClock) Now() time.Time {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by the given duration
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.now = f.now.Add(d)
+}
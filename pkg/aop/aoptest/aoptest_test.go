@@ -0,0 +1,125 @@
+package aoptest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/aop"
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingAdvice(t *testing.T) {
+	t.Run("records_invocations", func(t *testing.T) {
+		// given
+		mgr := NewManager(t, "recording")
+		recorder := NewRecordingAdvice()
+		mgr.RegisterJoinPoint(aop.NewRegexPointcut(".*Recorded\\d$"), recorder)
+
+		st := &sampleStruct{}
+
+		// when
+		_ = st.Recorded1(context.Background())
+		err := st.Recorded2(context.Background())
+
+		// then
+		assert.NotNil(t, err)
+		recorder.AssertInvoked(t, "Recorded1")
+		recorder.AssertInvoked(t, "(*sampleStruct).Recorded2")
+		recorder.AssertNotInvoked(t, "Unmatched")
+		recorder.AssertOrder(t, "Recorded1", "Recorded2")
+		invocations := recorder.Invocations()
+		assert.Equal(t, 4, len(invocations))
+		assert.Equal(t, AfterFrame, invocations[3].Frame)
+		assert.Equal(t, err, invocations[3].Err)
+	})
+
+	t.Run("failed_assertions", func(t *testing.T) {
+		// given
+		NewManager(t, "recordingFailures")
+		recorder := NewRecordingAdvice()
+		aop.RegisterJoinPoint(aop.NewRegexPointcut(".*Recorded\\d$"), recorder)
+		_ = (&sampleStruct{}).Recorded1(context.Background())
+
+		// when
+		mockT := &recordingT{TB: t}
+		invoked := recorder.AssertInvoked(mockT, "Recorded2")
+		ordered := recorder.AssertOrder(mockT, "Recorded2", "Recorded1")
+		notInvoked := recorder.AssertNotInvoked(mockT, "Recorded1")
+
+		// then
+		assert.False(t, invoked)
+		assert.False(t, ordered)
+		assert.False(t, notInvoked)
+		assert.Equal(t, 3, mockT.errors)
+	})
+}
+
+func TestNewManager(t *testing.T) {
+	// given
+	aop.InitAOP("original")
+
+	// when
+	t.Run("isolated", func(t *testing.T) {
+		NewManager(t, "isolated")
+		assert.Equal(t, "isolated", aop.GetServiceName())
+	})
+
+	// then
+	assert.Equal(t, "original", aop.GetServiceName())
+}
+
+func TestFakeClock(t *testing.T) {
+	// given
+	NewManager(t, "fakeClock")
+	clock := NewFakeClock(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	logger, hook := logtest.NewNullLogger()
+	ctx := logging.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+	aop.RegisterJoinPoint(aop.NewRegexPointcut(".*Recorded\\d$"), aop.NewSlowCallAdvice(time.Minute))
+
+	// when
+	_ = (&sampleStruct{during: func() { clock.Advance(2 * time.Minute) }}).Recorded1(ctx)
+
+	// then
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 2, 0, 0, time.UTC), clock.Now())
+	assert.Equal(t, 1, len(hook.AllEntries()))
+}
+
+type sampleStruct struct {
+	during func()
+}
+
+func (s *sampleStruct) Recorded1(ctx context.Context) (err error) {
+	ctx = aop.Before(ctx)
+	defer func() { aop.After(ctx, err) }()
+
+	if s.during != nil {
+		s.during()
+	}
+
+	return nil
+}
+
+func (s *sampleStruct) Recorded2(ctx context.Context) (err error) {
+	ctx = aop.Before(ctx)
+	defer func() { aop.After(ctx, err) }()
+
+	return errors.New("failed")
+}
+
+// recordingT counts reported errors instead of failing the test
+type recordingT struct {
+	testing.TB
+	errors int
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors++
+}
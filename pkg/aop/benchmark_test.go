@@ -0,0 +1,163 @@
+package aop
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmatchedMethodDoesNotAllocate(t *testing.T) {
+	// given
+	InitAOP("noAllocations")
+	RegisterJoinPoint(NewRegexPointcut(".*DoesNotExist$"), &noopAdvice{})
+
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+	_ = st.WovenMethod(ctx)
+
+	// when
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = st.WovenMethod(ctx)
+	})
+
+	// then
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestMatchedMethodAllocatesOnce(t *testing.T) {
+	// given
+	InitAOP("singleAllocation")
+	RegisterJoinPoint(NewRegexPointcut(".*WovenMethod$"), &noopAdvice{})
+
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+	_ = st.WovenMethod(ctx)
+
+	// when
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = st.WovenMethod(ctx)
+	})
+
+	// then
+	assert.Equal(t, float64(1), allocs)
+}
+
+func BenchmarkUnwoven(b *testing.B) {
+	InitAOP("benchUnwoven")
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = st.UnwovenMethod(ctx)
+	}
+}
+
+func BenchmarkWovenNoMatch(b *testing.B) {
+	InitAOP("benchNoMatch")
+	RegisterJoinPoint(NewRegexPointcut(".*DoesNotExist$"), &noopAdvice{})
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = st.WovenMethod(ctx)
+	}
+}
+
+func BenchmarkWovenNoopAdvice(b *testing.B) {
+	InitAOP("benchNoop")
+	RegisterJoinPoint(NewRegexPointcut(".*WovenMethod$"), &noopAdvice{})
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = st.WovenMethod(ctx)
+	}
+}
+
+func BenchmarkWovenNamedNoopAdvice(b *testing.B) {
+	InitAOP("benchNamedNoop")
+	RegisterJoinPoint(NewRegexPointcut(".*NamedMethod$"), &noopAdvice{})
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = st.NamedMethod(ctx)
+	}
+}
+
+func BenchmarkWovenNoopAdviceParallel(b *testing.B) {
+	InitAOP("benchNoopParallel")
+	RegisterJoinPoint(NewRegexPointcut(".*WovenMethod$"), &noopAdvice{})
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = st.WovenMethod(ctx)
+		}
+	})
+}
+
+func BenchmarkWovenTimedAdvice(b *testing.B) {
+	InitAOP("benchTimed")
+	RegisterJoinPoint(NewRegexPointcut(".*WovenMethod$"), NewTimedFuncAdvice("benchTimedAdvice", "for benchmarking"))
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = st.WovenMethod(ctx)
+	}
+}
+
+func BenchmarkWovenTimedAdviceDeepStack(b *testing.B) {
+	InitAOP("benchTimedDeep")
+	RegisterJoinPoint(NewRegexPointcut(".*WovenMethod$"), NewTimedFuncAdvice("benchTimedAdviceDeep", "for benchmarking"))
+	st := &benchSampleStruct{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = st.atDepth(ctx, 50)
+	}
+}
+
+type benchSampleStruct struct {
+}
+
+// atDepth calls WovenMethod depth frames further down the stack
+//
+//go:noinline
+func (s *benchSampleStruct) atDepth(ctx context.Context, depth int) error {
+	if depth == 0 {
+		return s.WovenMethod(ctx)
+	}
+	return s.atDepth(ctx, depth-1)
+}
+
+//go:noinline
+func (s *benchSampleStruct) UnwovenMethod(ctx context.Context) (err error) {
+	return nil
+}
+
+//go:noinline
+func (s *benchSampleStruct) WovenMethod(ctx context.Context) (err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	return nil
+}
+
+//go:noinline
+func (s *benchSampleStruct) NamedMethod(ctx context.Context) (err error) {
+	ctx = BeforeNamed(ctx, "benchSampleStruct.NamedMethod")
+	defer func() { After(ctx, err) }()
+
+	return nil
+}
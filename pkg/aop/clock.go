@@ -0,0 +1,32 @@
+package aop
+
+import "time"
+
+// Clock is the source of time used by the timing advices
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+var clock Clock = realClock{}
+
+// SetClock replaces the clock used by the timing advices returning the one previously in place, passing nil
+// restores the real clock
+func SetClock(c Clock) Clock {
+	previous := clock
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+	return previous
+}
+
+// since is the clock aware equivalent of time.Since
+func since(t time.Time) time.Duration {
+	return clock.Now().Sub(t)
+}
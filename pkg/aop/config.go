@@ -0,0 +1,326 @@
+package aop
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/config"
+	"github.com/jfbramlett/go-aop/pkg/metrics"
+)
+
+const (
+	// TimedAdviceName is the name of the advice factory for NewTimedFuncAdvice
+	TimedAdviceName = "timed"
+	// SpanAdviceName is the name of the advice factory for NewSpanFuncAdvice
+	SpanAdviceName = "span"
+	// LoggingAdviceName is the name of the advice factory for NewLoggingFuncAdvice
+	LoggingAdviceName = "logging"
+	// SlowCallAdviceName is the name of the advice factory for NewSlowCallAdvice
+	SlowCallAdviceName = "slow"
+	// ThroughputAdviceName is the name of the advice factory for NewThroughputFuncAdvice
+	ThroughputAdviceName = "throughput"
+	// ProfilingAdviceName is the name of the advice factory for NewProfilingAdvice
+	ProfilingAdviceName = "profiling"
+	// RetryAdviceName is the name of the advice factory for NewRetryAdvice
+	RetryAdviceName = "retry"
+
+	optionName             = "name"
+	optionDescription      = "description"
+	optionThreshold        = "threshold"
+	optionLimit            = "limit"
+	optionInterval         = "interval"
+	optionBuckets          = "buckets"
+	optionCardinalityLimit = "cardinalityLimit"
+	optionArgs             = "args"
+	optionBaggageLabels    = "baggageLabels"
+	optionAttempts         = "attempts"
+	optionBackoff          = "backoff"
+)
+
+// ErrNotInitialized is returned when configuring join points before InitAOP has been called
+var ErrNotInitialized = errors.New("aop has not been initialized")
+
+// JoinPointConfig describes a single join point, the pointcut is a regex matched against the method name and
+// the advice is the name of a registered advice factory
+type JoinPointConfig struct {
+	Pointcut string                 `json:"pointcut"`
+	Advice   string                 `json:"advice"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+// Config is the set of join points to register at startup
+type Config struct {
+	JoinPoints []JoinPointConfig `json:"joinPoints"`
+}
+
+// AdviceFactory creates a new advice from the options given in the config
+type AdviceFactory func(options map[string]interface{}) (Advice, error)
+
+// adviceBuilder checks the options of a join point, returning the func that creates its advice. Configure checks
+// every join point before creating any of the advices, as creating some (timed and throughput) registers metrics
+type adviceBuilder func(options map[string]interface{}) (func() (Advice, error), error)
+
+var adviceBuildersMux sync.RWMutex
+var adviceBuilders = map[string]adviceBuilder{
+	TimedAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		name, err := requiredStringOption(options, optionName)
+		if err != nil {
+			return nil, err
+		}
+		buckets, err := floatsOption(options, optionBuckets)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intOption(options, optionCardinalityLimit, 0)
+		if err != nil {
+			return nil, err
+		}
+		baggageLabels, err := stringsOption(options, optionBaggageLabels)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := timedLabelNames(baggageLabels); err != nil {
+			return nil, err
+		}
+		opts := []TimedFuncOption{WithCardinalityLimit(limit), WithBaggageLabels(baggageLabels...)}
+		if buckets != nil {
+			opts = append(opts, WithHistogram(buckets...))
+		}
+		return func() (Advice, error) {
+			return NewTimedFuncAdviceWithOptions(name, stringOption(options, optionDescription, name), opts...)
+		}, nil
+	},
+	SpanAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		// args is a safe-list of method regexes whose arguments may be recorded on their spans
+		patterns, err := stringsOption(options, optionArgs)
+		if err != nil {
+			return nil, err
+		}
+		opts := make([]SpanFuncOption, 0, len(patterns))
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q in option %q: %v", pattern, optionArgs, err)
+			}
+			opts = append(opts, WithSpanArgs(NewRegexPointcut(pattern)))
+		}
+		return func() (Advice, error) {
+			return NewSpanFuncAdviceWithOptions(opts...), nil
+		}, nil
+	},
+	LoggingAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		return func() (Advice, error) {
+			return NewLoggingFuncAdvice(), nil
+		}, nil
+	},
+	SlowCallAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		threshold, err := durationOption(options, optionThreshold, time.Second)
+		if err != nil {
+			return nil, err
+		}
+		interval, err := durationOption(options, optionInterval, time.Second)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intOption(options, optionLimit, defaultSlowCallLimit)
+		if err != nil {
+			return nil, err
+		}
+		return func() (Advice, error) {
+			return NewSlowCallAdvice(threshold, WithSlowCallRateLimit(limit, interval)), nil
+		}, nil
+	},
+	ThroughputAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		name, err := requiredStringOption(options, optionName)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intOption(options, optionCardinalityLimit, 0)
+		if err != nil {
+			return nil, err
+		}
+		return func() (Advice, error) {
+			return NewThroughputFuncAdviceWithBackend(name, stringOption(options, optionDescription, name),
+				metrics.LimitCardinality(metrics.NewPrometheusBackend(nil), limit, boundedLabels...))
+		}, nil
+	},
+	ProfilingAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		return func() (Advice, error) {
+			return NewProfilingAdvice(), nil
+		}, nil
+	},
+	RetryAdviceName: func(options map[string]interface{}) (func() (Advice, error), error) {
+		attempts, err := intOption(options, optionAttempts, defaultRetryAttempts)
+		if err != nil {
+			return nil, err
+		}
+		backoff, err := durationOption(options, optionBackoff, defaultRetryBackoff)
+		if err != nil {
+			return nil, err
+		}
+		return func() (Advice, error) {
+			return NewRetryAdvice(attempts, backoff), nil
+		}, nil
+	},
+}
+
+// RegisterAdviceFactory registers a factory under the given name so it can be referenced from config, registering
+// a factory under an existing name replaces it. The factory is given the options unchecked, so it is only called
+// once every join point in the config has been checked
+func RegisterAdviceFactory(name string, factory AdviceFactory) {
+	adviceBuildersMux.Lock()
+	defer adviceBuildersMux.Unlock()
+	adviceBuilders[name] = func(options map[string]interface{}) (func() (Advice, error), error) {
+		return func() (Advice, error) {
+			return factory(options)
+		}, nil
+	}
+}
+
+// ConfigureFromConfig builds the join point config from the given builder and registers each of the join points,
+// InitAOP must have been called first
+func ConfigureFromConfig(builder *config.Builder) error {
+	cfg := Config{}
+	if err := builder.Build(&cfg); err != nil {
+		return err
+	}
+
+	return Configure(cfg)
+}
+
+// Configure registers each of the join points in the given config, InitAOP must have been called first
+func Configure(cfg Config) error {
+	if globalAspectMgr == nil {
+		return ErrNotInitialized
+	}
+
+	// check every entry before creating any of the advices, creating an advice may register metrics so a bad
+	// entry found part way through would leave us partially configured
+	builds := make([]func() (Advice, error), 0, len(cfg.JoinPoints))
+	for i, jpCfg := range cfg.JoinPoints {
+		adviceBuildersMux.RLock()
+		builder, found := adviceBuilders[jpCfg.Advice]
+		adviceBuildersMux.RUnlock()
+		if !found {
+			return fmt.Errorf("join point %d: unknown advice %q", i, jpCfg.Advice)
+		}
+
+		if _, err := regexp.Compile(jpCfg.Pointcut); err != nil {
+			return fmt.Errorf("join point %d: invalid pointcut %q: %v", i, jpCfg.Pointcut, err)
+		}
+
+		build, err := builder(jpCfg.Options)
+		if err != nil {
+			return fmt.Errorf("join point %d: invalid options for advice %q: %v", i, jpCfg.Advice, err)
+		}
+		builds = append(builds, build)
+	}
+
+	// build all of the advices before registering any of them
+	joinPoints := make([]joinPoint, 0, len(builds))
+	for i, build := range builds {
+		advice, err := build()
+		if err != nil {
+			return fmt.Errorf("join point %d: failed to create advice %q: %v", i, cfg.JoinPoints[i].Advice, err)
+		}
+
+		joinPoints = append(joinPoints, joinPoint{pointcut: NewRegexPointcut(cfg.JoinPoints[i].Pointcut), advice: advice})
+	}
+
+	for _, jp := range joinPoints {
+		RegisterJoinPoint(jp.pointcut, jp.advice)
+	}
+
+	return nil
+}
+
+func stringOption(options map[string]interface{}, key string, defaultValue string) string {
+	if val, found := options[key]; found {
+		return fmt.Sprintf("%v", val)
+	}
+	return defaultValue
+}
+
+func requiredStringOption(options map[string]interface{}, key string) (string, error) {
+	val := stringOption(options, key, "")
+	if val == "" {
+		return "", fmt.Errorf("missing required option %q", key)
+	}
+	return val, nil
+}
+
+func durationOption(options map[string]interface{}, key string, defaultValue time.Duration) (time.Duration, error) {
+	val, found := options[key]
+	if !found {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(fmt.Sprintf("%v", val))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for option %q: %v", key, err)
+	}
+	return duration, nil
+}
+
+func intOption(options map[string]interface{}, key string, defaultValue int) (int, error) {
+	val, found := options[key]
+	if !found {
+		return defaultValue, nil
+	}
+
+	// numbers decoded from json arrive as float64
+	switch v := val.(type) {
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("invalid integer for option %q: %v", key, val)
+	}
+}
+
+func stringsOption(options map[string]interface{}, key string) ([]string, error) {
+	val, found := options[key]
+	if !found {
+		return nil, nil
+	}
+
+	values, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid list for option %q: %v", key, val)
+	}
+
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string in option %q: %v", key, v)
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+func floatsOption(options map[string]interface{}, key string) ([]float64, error) {
+	val, found := options[key]
+	if !found {
+		return nil, nil
+	}
+
+	values, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid list for option %q: %v", key, val)
+	}
+
+	floats := make([]float64, 0, len(values))
+	for _, v := range values {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("invalid number in option %q: %v", key, v)
+		}
+		floats = append(floats, f)
+	}
+	return floats, nil
+}
@@ -0,0 +1,170 @@
+package aop
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jfbramlett/go-aop/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureFromConfig(t *testing.T) {
+	t.Run("register_from_file", func(t *testing.T) {
+		// given
+		collector := &aspectCollector{methodCalls: make([]methodCall, 0)}
+		RegisterAdviceFactory("testCounting", func(options map[string]interface{}) (Advice, error) {
+			return &countingAspect{collector: collector}, nil
+		})
+
+		cfgFile := writeConfigFile(t, `{"joinPoints": [
+			{"pointcut": ".*Method1$", "advice": "testCounting"},
+			{"pointcut": ".*ConfiguredCall\\d$", "advice": "timed", "options": {"name": "testConfiguredTimed"}},
+			{"pointcut": ".*ConfiguredCall\\d$", "advice": "slow", "options": {"threshold": "1s", "limit": 5}}
+		]}`)
+
+		InitAOP("configured")
+
+		// when
+		err := ConfigureFromConfig(config.NewBuilder().WithSource(config.NewFileSource(cfgFile)))
+		st := sampleStruct{collector: collector}
+		_, _ = st.Method1("arg1", 1)
+		_, _ = (&configTestSampleStruct{}).ConfiguredCall1(context.Background())
+
+		// then
+		require.Nil(t, err)
+		assert.Equal(t, []methodCall{{BeforeFrame, "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1", CountAdvice},
+			{MethodFrame, "Method1", MethodAdvice},
+			{AfterFrame, "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1", CountAdvice},
+		}, collector.methodCalls)
+		validateMetrics(t, "configured", []string{"func1"}, []string{"ConfiguredCall1"}, "testConfiguredTimed", 1, 0)
+	})
+
+	t.Run("unknown_advice", func(t *testing.T) {
+		// given
+		InitAOP("configuredUnknown")
+
+		// when
+		err := Configure(Config{JoinPoints: []JoinPointConfig{{Pointcut: ".*", Advice: "doesNotExist"}}})
+
+		// then
+		assert.NotNil(t, err)
+	})
+
+	t.Run("invalid_options", func(t *testing.T) {
+		// given
+		InitAOP("configuredInvalid")
+
+		// when
+		err := Configure(Config{JoinPoints: []JoinPointConfig{
+			{Pointcut: ".*", Advice: SlowCallAdviceName, Options: map[string]interface{}{optionThreshold: "soon"}},
+		}})
+
+		// then
+		assert.NotNil(t, err)
+	})
+
+	t.Run("span_args_option", func(t *testing.T) {
+		// given
+		InitAOP("configuredSpanArgs")
+
+		// when
+		valid := Configure(Config{JoinPoints: []JoinPointConfig{
+			{Pointcut: ".*", Advice: SpanAdviceName, Options: map[string]interface{}{optionArgs: []interface{}{".*Order.*"}}},
+		}})
+		invalid := Configure(Config{JoinPoints: []JoinPointConfig{
+			{Pointcut: ".*", Advice: SpanAdviceName, Options: map[string]interface{}{optionArgs: ".*Order.*"}},
+		}})
+		invalidPattern := Configure(Config{JoinPoints: []JoinPointConfig{
+			{Pointcut: ".*", Advice: SpanAdviceName, Options: map[string]interface{}{optionArgs: []interface{}{".*Order("}}},
+		}})
+
+		// then
+		assert.Nil(t, valid)
+		assert.NotNil(t, invalid)
+		assert.NotNil(t, invalidPattern)
+	})
+
+	t.Run("invalid_later_entry", func(t *testing.T) {
+		// given
+		InitAOP("configuredInvalidLater")
+
+		// when
+		err := Configure(Config{JoinPoints: []JoinPointConfig{
+			{Pointcut: ".*", Advice: TimedAdviceName, Options: map[string]interface{}{optionName: "testConfiguredInvalidLater"}},
+			{Pointcut: ".*", Advice: ThroughputAdviceName, Options: map[string]interface{}{optionName: "testConfiguredInvalidLater"}},
+			{Pointcut: ".*", Advice: SpanAdviceName, Options: map[string]interface{}{optionArgs: []interface{}{".*Order("}}},
+		}})
+
+		// then nothing was registered by the earlier entries
+		assert.NotNil(t, err)
+		for _, name := range []string{"testConfiguredInvalidLater_quantiles", "testConfiguredInvalidLater_invocations_total"} {
+			unclaimed := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: "for testing"})
+			assert.Nil(t, prometheus.Register(unclaimed), name)
+			prometheus.Unregister(unclaimed)
+		}
+	})
+
+	t.Run("invalid_pointcut", func(t *testing.T) {
+		// given
+		InitAOP("configuredInvalidPointcut")
+
+		// when
+		err := Configure(Config{JoinPoints: []JoinPointConfig{{Pointcut: ".*Method(", Advice: SpanAdviceName}}})
+
+		// then
+		assert.NotNil(t, err)
+	})
+
+	t.Run("retry_advice", func(t *testing.T) {
+		// given
+		InitAOP("configuredRetry")
+
+		// when
+		err := Configure(Config{JoinPoints: []JoinPointConfig{
+			{Pointcut: ".*RetriedMethod$", Advice: RetryAdviceName, Options: map[string]interface{}{optionAttempts: float64(2), optionBackoff: "1ms"}},
+		}})
+		tStruct := &retryTestSampleStruct{failures: 5}
+		callErr := tStruct.RetriedMethod(context.Background())
+
+		// then
+		require.Nil(t, err)
+		assert.Equal(t, errRetryTest, callErr)
+		assert.Equal(t, 2, tStruct.calls)
+	})
+
+	t.Run("not_initialized", func(t *testing.T) {
+		// given
+		globalAspectMgr = nil
+
+		// when
+		err := Configure(Config{})
+
+		// then
+		assert.Equal(t, ErrNotInitialized, err)
+	})
+}
+
+type configTestSampleStruct struct {
+}
+
+func (s *configTestSampleStruct) ConfiguredCall1(ctx context.Context) (result string, err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	return "success", nil
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "aopconfig")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	filename := filepath.Join(dir, "config.json")
+	require.Nil(t, ioutil.WriteFile(filename, []byte(content), 0600))
+	return filename
+}
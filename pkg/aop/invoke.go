@@ -0,0 +1,56 @@
+package aop
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error handed to the After advice when the method panicked
+type PanicError struct {
+	// Value is the value the method panicked with
+	Value interface{}
+	// Stack is the stack of the goroutine at the point of the panic
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Invoke runs the Before advice for the calling method, updating ctx in place, and returns the func that runs the
+// After advice. It is intended to be deferred in a single statement with a pointer to the method's named error:
+//
+//	func (s *MyStruct) MyMethod(ctx context.Context) (err error) {
+//		defer aop.Invoke(&ctx)(&err)
+//		...
+//	}
+//
+// The After advice always sees the context produced by Before and the final value of err. If the method panics
+// the After advice is given a *PanicError and the panic is then allowed to continue. err may be nil for methods
+// that don't return an error
+func Invoke(ctx *context.Context) func(err *error) {
+	var advised bool
+	*ctx, advised = before(*ctx, 1)
+	if !advised {
+		// nothing to run after
+		return noopAfter
+	}
+
+	invokedCtx := *ctx
+	return func(err *error) {
+		if r := recover(); r != nil {
+			After(invokedCtx, &PanicError{Value: r, Stack: debug.Stack()})
+			panic(r)
+		}
+
+		var result error
+		if err != nil {
+			result = *err
+		}
+		After(invokedCtx, result)
+	}
+}
+
+func noopAfter(_ *error) {
+}
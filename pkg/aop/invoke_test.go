@@ -0,0 +1,157 @@
+package aop
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoke(t *testing.T) {
+	t.Run("run_success", func(t *testing.T) {
+		// given
+		InitAOP("invokeSuccess")
+		recorder := &errRecordingAspect{}
+		RegisterJoinPoint(NewRegexPointcut(".*InvokedMethod\\d$"), recorder)
+
+		st := &invokeTestSampleStruct{}
+
+		// when
+		err := st.InvokedMethod1(context.Background(), nil)
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"github.com/jfbramlett/go-aop/pkg/aop.(*invokeTestSampleStruct).InvokedMethod1"}, recorder.methods)
+		assert.Equal(t, []error{nil}, recorder.errs)
+		assert.True(t, recorder.sawBeforeCtx)
+	})
+
+	t.Run("run_error", func(t *testing.T) {
+		// given
+		InitAOP("invokeError")
+		recorder := &errRecordingAspect{}
+		RegisterJoinPoint(NewRegexPointcut(".*InvokedMethod\\d$"), recorder)
+
+		st := &invokeTestSampleStruct{}
+		expectedErr := errors.New("failed")
+
+		// when
+		err := st.InvokedMethod1(context.Background(), expectedErr)
+
+		// then
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, []error{expectedErr}, recorder.errs)
+	})
+
+	t.Run("run_panic", func(t *testing.T) {
+		// given
+		InitAOP("invokePanic")
+		recorder := &errRecordingAspect{}
+		RegisterJoinPoint(NewRegexPointcut(".*InvokedMethod\\d$"), recorder)
+
+		st := &invokeTestSampleStruct{}
+
+		// when
+		assert.PanicsWithValue(t, "boom", func() { st.InvokedMethod2(context.Background()) })
+
+		// then
+		require.Equal(t, 1, len(recorder.errs))
+		panicErr, ok := recorder.errs[0].(*PanicError)
+		require.True(t, ok)
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.Contains(t, string(panicErr.Stack), "InvokedMethod2")
+	})
+
+	t.Run("uncomparable_context", func(t *testing.T) {
+		// given
+		InitAOP("invokeUncomparable")
+		recorder := &passThroughAspect{}
+		RegisterJoinPoint(NewRegexPointcut(".*InvokedMethod1$"), recorder)
+		st := &invokeTestSampleStruct{}
+		ctx := uncomparableCtx{Context: context.Background(), values: map[string]string{}}
+
+		// when
+		assert.NotPanics(t, func() {
+			_ = st.InvokedMethod1(ctx, nil)
+			st.InvokedMethod3(ctx)
+		})
+
+		// then
+		assert.Equal(t, 1, recorder.afters)
+	})
+
+	t.Run("no_advice", func(t *testing.T) {
+		// given
+		InitAOP("invokeNoAdvice")
+		st := &invokeTestSampleStruct{}
+		ctx := context.Background()
+		st.InvokedMethod3(ctx)
+
+		// when (the deferred func is called indirectly so a named err would always escape, hence no err here)
+		allocs := testing.AllocsPerRun(100, func() {
+			st.InvokedMethod3(ctx)
+		})
+
+		// then
+		assert.Equal(t, float64(0), allocs)
+	})
+}
+
+type invokeTestSampleStruct struct {
+}
+
+func (s *invokeTestSampleStruct) InvokedMethod1(ctx context.Context, result error) (err error) {
+	defer Invoke(&ctx)(&err)
+
+	return result
+}
+
+func (s *invokeTestSampleStruct) InvokedMethod2(ctx context.Context) {
+	defer Invoke(&ctx)(nil)
+
+	panic("boom")
+}
+
+func (s *invokeTestSampleStruct) InvokedMethod3(ctx context.Context) {
+	defer Invoke(&ctx)(nil)
+}
+
+type invokeCtxKey struct{}
+
+// errRecordingAspect records the methods and errors seen by After, checking After sees the context from Before
+type errRecordingAspect struct {
+	methods      []string
+	errs         []error
+	sawBeforeCtx bool
+}
+
+func (e *errRecordingAspect) Before(ctx context.Context) context.Context {
+	return context.WithValue(ctx, invokeCtxKey{}, true)
+}
+
+func (e *errRecordingAspect) After(ctx context.Context, err error) {
+	e.methods = append(e.methods, AspectFromContext(ctx).MethodName)
+	e.errs = append(e.errs, err)
+	e.sawBeforeCtx = ctx.Value(invokeCtxKey{}) != nil
+}
+
+// uncomparableCtx is a context whose dynamic type can't be compared with ==
+type uncomparableCtx struct {
+	context.Context
+	values map[string]string
+}
+
+// passThroughAspect leaves the context alone, counting the calls to After
+type passThroughAspect struct {
+	afters int
+}
+
+func (p *passThroughAspect) Before(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (p *passThroughAspect) After(ctx context.Context, err error) {
+	p.afters++
+}
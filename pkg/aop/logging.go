@@ -4,7 +4,7 @@ import (
 	"context"
 	"github.com/jfbramlett/go-aop/pkg/logging"
 	"github.com/jfbramlett/go-aop/pkg/stackutils"
-	"github.com/sirupsen/logrus"
+	"github.com/jfbramlett/go-aop/pkg/tracing"
 )
 
 type loggingCtxKey struct{}
@@ -22,13 +22,17 @@ type loggingAdvice struct {
 func (s *loggingAdvice) Before(ctx context.Context) context.Context {
 	method := stackutils.BasicQualifierFromMethod(ctx.Value(Method).(string))
 
-	logger, newCtx := logging.UpdateInContext(ctx, logrus.Fields{"name": method})
+	fields := tracing.LogFields(ctx)
+	fields["name"] = method
+	logger, newCtx := logging.UpdateInContext(ctx, fields)
 	logger.Debug("starting")
 	return newCtx
 }
 
 func (s *loggingAdvice) After(ctx context.Context, err error) {
 	logger, _ := logging.LoggerFromContext(ctx)
+	// the span may have been started after this advice's Before
+	logger = logger.WithFields(tracing.LogFields(ctx))
 	if err != nil {
 		logger.Debugf("completed with error %s", err)
 	} else {
@@ -3,9 +3,11 @@ package aop
 import (
 	"context"
 	"fmt"
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/jfbramlett/go-aop/pkg/metrics"
 	"github.com/jfbramlett/go-aop/pkg/stackutils"
+	"github.com/jfbramlett/go-aop/pkg/tracing"
 	"github.com/prometheus/client_golang/prometheus"
-	"runtime"
 	"time"
 )
 
@@ -18,38 +20,227 @@ const (
 	callingMethodKey 	= "calling_method"
 	methodNameKey 		= "method"
 	resultKey 			= "result"
+	traceIDKey			= "trace_id"
 )
 
+// boundedLabels are the labels with a handful of values, kept when the other labels are collapsed by a cardinality
+// limit
+var boundedLabels = []string{serviceNameKey, resultKey}
+
 type metricCtxKey struct {}
 var timerMetricCtxKey = metricCtxKey{}
 
-// NewTimedFuncAdvice creates a new Advice that will capture method execution time
+// TimedFuncOption is used to configure the metric created by a timed func advice
+type TimedFuncOption func(t *timedFuncOptions)
+
+type timedFuncOptions struct {
+	objectives         map[float64]float64
+	buckets            []float64
+	histogram          bool
+	nativeBucketFactor float64
+	constLabels        prometheus.Labels
+	registerer         prometheus.Registerer
+	backend            metrics.Backend
+	cardinalityLimit   int
+	baggageLabels      []string
+}
+
+// WithObjectives sets the quantile objectives of the Summary used to record timings
+func WithObjectives(objectives map[float64]float64) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.objectives = objectives
+	}
+}
+
+// DefaultHistogramBuckets are the buckets used by WithHistogram when none are given, the prometheus default buckets
+// scaled to milliseconds since that's the unit timings are recorded in
+var DefaultHistogramBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// WithHistogram records timings in a Histogram with the given (classic) buckets rather than a Summary, passing no
+// buckets uses DefaultHistogramBuckets. Buckets are in milliseconds. Histograms carry the trace id of the active span
+// as an exemplar, this (or WithNativeHistogram) is required to get exemplars since Summaries can't carry them
+func WithHistogram(buckets ...float64) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.histogram = true
+		t.buckets = buckets
+		if len(buckets) == 0 {
+			t.buckets = DefaultHistogramBuckets
+		}
+	}
+}
+
+// WithNativeHistogram records timings in a native Histogram with the given bucket growth factor (e.g. 1.1) rather
+// than a Summary, this can be combined with WithHistogram to also expose classic buckets
+func WithNativeHistogram(bucketFactor float64) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.histogram = true
+		t.nativeBucketFactor = bucketFactor
+	}
+}
+
+// WithConstLabels adds labels with fixed values to the metric
+func WithConstLabels(labels prometheus.Labels) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.constLabels = labels
+	}
+}
+
+// WithRegisterer registers the metric with the given registerer rather than the global prometheus registry
+func WithRegisterer(registerer prometheus.Registerer) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.registerer = registerer
+	}
+}
+
+// WithBackend records timings through the given metrics backend (e.g. StatsD) rather than creating a prometheus
+// metric, the other options only apply to prometheus metrics so are ignored
+func WithBackend(backend metrics.Backend) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.backend = backend
+	}
+}
+
+// WithCardinalityLimit caps the distinct label combinations recorded, as the calling method comes from the stack
+// there is no natural bound on it. Combinations beyond the limit are recorded with the method, calling method and
+// baggage labels set to "other" (keeping the service name and result) and counted in the metric_labels_dropped_total
+// metric
+func WithCardinalityLimit(limit int) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.cardinalityLimit = limit
+	}
+}
+
+// WithBaggageLabels adds a label for each of the baggage keys, recording the value of the item in the baggage of
+// the request. Only keys on the tracing baggage allow-list are recorded, other keys are left empty. Label names
+// are the keys with any characters not allowed by prometheus replaced with '_', keys giving the same label as
+// another key or a built in label are rejected when the advice is created
+func WithBaggageLabels(keys ...string) TimedFuncOption {
+	return func(t *timedFuncOptions) {
+		t.baggageLabels = keys
+	}
+}
+
+// NewTimedFuncAdvice creates a new Advice that will capture method execution time, use
+// NewTimedFuncAdviceWithOptions to configure the metric or to be told if the metric could not be registered. If
+// the metric can't be registered the error is logged and the advice records nothing. Timings are recorded in a
+// Summary so they don't carry trace id exemplars, use NewTimedFuncAdviceWithOptions with WithHistogram for those
 func NewTimedFuncAdvice(name string, description string) Advice {
+	advice, err := NewTimedFuncAdviceWithOptions(name, description)
+	if err != nil {
+		logger, _ := logging.LoggerFromContext(context.Background())
+		logger.WithError(err).Errorf("failed to create timed advice %s, timings will not be recorded", name)
+		return noopAdvice{}
+	}
+	return advice
+}
+
+// NewTimedFuncAdviceWithOptions creates a new Advice that will capture method execution time (in milliseconds). By
+// default timings are recorded in a Summary registered with the global prometheus registry, if a metric with the
+// same name is already registered that metric is reused. Summaries can't carry exemplars, pass WithHistogram or
+// WithNativeHistogram to link timings to traces
+func NewTimedFuncAdviceWithOptions(name string, description string, opts ...TimedFuncOption) (Advice, error) {
+	options := &timedFuncOptions{
+		objectives: map[float64]float64{0.5: 0.05, 0.95: 0.005},
+		registerer: prometheus.DefaultRegisterer,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
 
 	// Build the set of prometheus labels
-	promTags := []string {serviceNameKey, callingMethodKey, methodNameKey, resultKey}
-
-	// Create the Summary metric
-	quantiles := prometheus.NewSummaryVec(
-		prometheus.SummaryOpts{
-			Name:       fmt.Sprintf("%v_quantiles", name),
-			Help:       description,
-			Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.005},
-		},
-		promTags,
-	)
-
-	// Register it with the prometheus registry
-	err := prometheus.Register(quantiles)
+	promTags, err := timedLabelNames(options.baggageLabels)
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
+	}
+
+	if options.backend != nil {
+		timer, err := metrics.LimitCardinality(options.backend, options.cardinalityLimit, boundedLabels...).Timer(name, description, promTags)
+		if err != nil {
+			return nil, err
+		}
+		return &timedFuncAdvice{timer: timer, baggageLabels: options.baggageLabels}, nil
 	}
 
-	return &timedFuncAdvice{quantiles: quantiles}
+	var observer prometheus.ObserverVec
+	if options.histogram {
+		// with no classic buckets (and a native bucket factor) this is a native only histogram
+		observer = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:                        fmt.Sprintf("%v_histogram", name),
+				Help:                        description,
+				ConstLabels:                 options.constLabels,
+				Buckets:                     options.buckets,
+				NativeHistogramBucketFactor: options.nativeBucketFactor,
+			},
+			promTags,
+		)
+	} else {
+		// Create the Summary metric
+		observer = prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name:        fmt.Sprintf("%v_quantiles", name),
+				Help:        description,
+				Objectives:  options.objectives,
+				ConstLabels: options.constLabels,
+			},
+			promTags,
+		)
+	}
+
+	// Register it with the prometheus registry (reusing the metric if it is already registered)
+	collector, err := metrics.Register(options.registerer, observer)
+	if err != nil {
+		return nil, err
+	}
+	registered, ok := collector.(prometheus.ObserverVec)
+	if !ok {
+		return nil, fmt.Errorf("metric %s is already registered as a different type", name)
+	}
+
+	timer := metrics.NewPrometheusTimer(registered)
+	if options.cardinalityLimit > 0 {
+		dropped, err := metrics.DroppedLabelsCounter(metrics.NewPrometheusBackend(options.registerer))
+		if err != nil {
+			return nil, err
+		}
+		timer = metrics.LimitTimer(timer, metrics.NewCardinalityLimiter(name, options.cardinalityLimit, dropped,
+			metrics.LabelPositions(promTags, boundedLabels...)...))
+	}
+
+	return &timedFuncAdvice{timer: timer, baggageLabels: options.baggageLabels}, nil
+}
+
+// timedLabelNames gets the labels of a timed metric recording the given baggage keys, failing if a key gives the
+// same label as another key or a built in label
+func timedLabelNames(baggageLabels []string) ([]string, error) {
+	labels := []string{serviceNameKey, callingMethodKey, methodNameKey, resultKey}
+	for _, key := range baggageLabels {
+		label := baggageLabelName(key)
+		for _, existing := range labels {
+			if label == existing {
+				return nil, fmt.Errorf("baggage key %q is recorded as label %s which is already used by the metric", key, label)
+			}
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// baggageLabelName converts a baggage key into a valid prometheus label name
+func baggageLabelName(key string) string {
+	label := []byte(key)
+	for i, c := range label {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			label[i] = '_'
+		}
+	}
+	return string(label)
 }
 
 type timedFuncAdvice struct {
-	quantiles 	*prometheus.SummaryVec
+	timer 	metrics.Timer
+	baggageLabels []string
 }
 
 func (t *timedFuncAdvice) Before(ctx context.Context) context.Context {
@@ -58,7 +249,7 @@ func (t *timedFuncAdvice) Before(ctx context.Context) context.Context {
 		return ctx
 	}
 
-	wrappedContext := context.WithValue(ctx, timerMetricCtxKey, time.Now())
+	wrappedContext := context.WithValue(ctx, timerMetricCtxKey, clock.Now())
 
 	return wrappedContext
 }
@@ -79,13 +270,17 @@ func (t *timedFuncAdvice) After(ctx context.Context, err error) {
 		result = resultFailure
 	}
 
-	ms := float64(time.Since(timerStart).Nanoseconds()) / 1e6
-
-	values := []string {GetServiceName(), stackutils.MethodNameFromFullPath(t.getCallingMethod(aop.MethodName)),
+	values := []string {GetServiceName(), stackutils.MethodNameFromFullPath(CallingMethodFromContext(ctx)),
 		stackutils.MethodNameFromFullPath(aop.MethodName), result}
+	if len(t.baggageLabels) > 0 {
+		items := tracing.AllowedBaggage(ctx)
+		for _, key := range t.baggageLabels {
+			values = append(values, items[key])
+		}
+	}
 
 	// Log the metric
-	t.quantiles.WithLabelValues(values...).Observe(ms)
+	t.timer.Record(ctx, since(timerStart), values...)
 }
 
 func (t *timedFuncAdvice) getStartTime(ctx context.Context) (time.Time, bool) {
@@ -97,25 +292,3 @@ func (t *timedFuncAdvice) getStartTime(ctx context.Context) (time.Time, bool) {
 	}
 	return time.Time{}, false
 }
-
-func (t *timedFuncAdvice) getCallingMethod(toMethod string) string {
-	for i := 2;; i++ {
-		pc, _, _, ok := runtime.Caller(i)
-		details := runtime.FuncForPC(pc)
-		if ok && details != nil {
-			if details.Name() == toMethod {
-				pc, _, _, ok := runtime.Caller(i+1)
-				details := runtime.FuncForPC(pc)
-				if ok && details != nil {
-					return details.Name()
-				}
-				break
-			}
-		} else {
-			break
-		}
-	}
-
-	return UnknownMethod
-}
-
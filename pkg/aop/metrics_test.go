@@ -3,11 +3,19 @@ package aop
 import (
 	"context"
 	"errors"
+	"github.com/jfbramlett/go-aop/pkg/metrics"
+	"github.com/jfbramlett/go-aop/pkg/tracing"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
 	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestTimedFuncAdvice(t *testing.T) {
@@ -80,6 +88,384 @@ func TestTimedFuncAdvice(t *testing.T) {
 
 }
 
+func TestTimedFuncAdviceWithOptions(t *testing.T) {
+	t.Run("histogram_with_buckets", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedHistogram")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedHistogram", "for testing",
+			WithRegisterer(registry), WithHistogram(1, 10, 100), WithConstLabels(prometheus.Labels{"team": "core"}))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		metric := gatherSingleMetric(t, registry, "testTimedHistogram_histogram")
+		assert.True(t, doesLabelMatch(metric, "team", "core"))
+		assert.True(t, doesLabelMatch(metric, methodNameKey, "TimedMethod1"))
+		assert.Equal(t, uint64(1), metric.Histogram.GetSampleCount())
+		assert.Equal(t, 3, len(metric.Histogram.Bucket))
+	})
+
+	t.Run("histogram_default_buckets", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedDefaultBuckets")
+		defer SetClock(SetClock(&steppingClock{now: time.Now(), step: 20 * time.Millisecond}))
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedDefaultBuckets", "for testing",
+			WithRegisterer(registry), WithHistogram())
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		metric := gatherSingleMetric(t, registry, "testTimedDefaultBuckets_histogram")
+		assert.Equal(t, uint64(1), metric.Histogram.GetSampleCount())
+		assert.Equal(t, float64(20), metric.Histogram.GetSampleSum())
+		require.Equal(t, len(DefaultHistogramBuckets), len(metric.Histogram.Bucket))
+		assert.Equal(t, float64(25), metric.Histogram.Bucket[2].GetUpperBound())
+		assert.Equal(t, uint64(1), metric.Histogram.Bucket[2].GetCumulativeCount())
+		for _, bucket := range metric.Histogram.Bucket {
+			expected := uint64(0)
+			if bucket.GetUpperBound() >= 20 {
+				expected = 1
+			}
+			assert.Equal(t, expected, bucket.GetCumulativeCount(), "bucket %v", bucket.GetUpperBound())
+		}
+	})
+
+	t.Run("native_histogram", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedNativeHistogram")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedNativeHistogram", "for testing",
+			WithRegisterer(registry), WithNativeHistogram(1.1))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		metric := gatherSingleMetric(t, registry, "testTimedNativeHistogram_histogram")
+		assert.Equal(t, uint64(1), metric.Histogram.GetSampleCount())
+		assert.Equal(t, 0, len(metric.Histogram.Bucket))
+		assert.NotNil(t, metric.Histogram.Schema)
+	})
+
+	t.Run("summary_objectives", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedObjectives")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedObjectives", "for testing",
+			WithRegisterer(registry), WithObjectives(map[float64]float64{0.99: 0.001}))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		metric := gatherSingleMetric(t, registry, "testTimedObjectives_quantiles")
+		require.Equal(t, 1, len(metric.Summary.Quantile))
+		assert.Equal(t, 0.99, metric.Summary.Quantile[0].GetQuantile())
+	})
+
+	t.Run("histogram_exemplar", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, tracing.NewTracerWithReporter(tracing.TracingConfig{Service: "timedExemplar"}, spanReporter))
+
+		registry := prometheus.NewRegistry()
+		InitAOP("timedExemplar")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedExemplar", "for testing",
+			WithRegisterer(registry), WithHistogram(1000))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), NewSpanFuncAdvice())
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		finishedSpans := spanReporter.Flush()
+		require.Equal(t, 1, len(finishedSpans))
+		metric := gatherSingleMetric(t, registry, "testTimedExemplar_histogram")
+		require.Equal(t, 1, len(metric.Histogram.Bucket))
+		exemplar := metric.Histogram.Bucket[0].Exemplar
+		require.NotNil(t, exemplar)
+		assert.Equal(t, traceIDKey, exemplar.Label[0].GetName())
+		assert.Equal(t, finishedSpans[0].TraceID.String(), exemplar.Label[0].GetValue())
+	})
+
+	t.Run("summary_without_exemplar", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, tracing.NewTracerWithReporter(tracing.TracingConfig{Service: "timedNoExemplar"}, spanReporter))
+
+		registry := prometheus.NewRegistry()
+		InitAOP("timedNoExemplar")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedNoExemplar", "for testing", WithRegisterer(registry))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), NewSpanFuncAdvice())
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		require.Equal(t, 1, len(spanReporter.Flush()))
+		metric := gatherSingleMetric(t, registry, "testTimedNoExemplar_quantiles")
+		require.NotNil(t, metric.Summary)
+		assert.Nil(t, metric.Histogram)
+		assert.Equal(t, uint64(1), metric.Summary.GetSampleCount())
+	})
+
+	t.Run("statsd_backend", func(t *testing.T) {
+		// given
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(t, err)
+		defer listener.Close()
+		backend, err := metrics.NewStatsDBackend(metrics.StatsDConfig{Address: listener.LocalAddr().String(), DogStatsD: true})
+		require.Nil(t, err)
+		defer backend.Close()
+
+		InitAOP("timedStatsD")
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedStatsD", "for testing", WithBackend(backend))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod2(context.Background())
+		require.Nil(t, backend.Flush())
+
+		// then
+		assert.NotNil(t, err)
+		buf := make([]byte, 1024)
+		require.Nil(t, listener.SetReadDeadline(time.Now().Add(2*time.Second)))
+		n, _, err := listener.ReadFrom(buf)
+		require.Nil(t, err)
+		assert.Regexp(t, `^testTimedStatsD:[0-9.]+\|ms\|#service_name:timedStatsD,calling_method:func[0-9]+,method:TimedMethod2,result:failure$`,
+			string(buf[:n]))
+	})
+
+	t.Run("otel_backend", func(t *testing.T) {
+		// given
+		reader := sdkmetric.NewManualReader()
+		backend := metrics.NewOTelBackend(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+		InitAOP("timedOTel")
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedOTel", "for testing", WithBackend(backend))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, err = (&metricsTestSampleStruct{}).TimedMethod2(context.Background())
+
+		// then
+		assert.NotNil(t, err)
+		var rm metricdata.ResourceMetrics
+		require.Nil(t, reader.Collect(context.Background(), &rm))
+		require.Len(t, rm.ScopeMetrics, 1)
+		require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+		histogram := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+		require.Len(t, histogram.DataPoints, 1)
+		// the calling method is the test's closure
+		callingMethod, _ := histogram.DataPoints[0].Attributes.Value("calling_method")
+		assert.Regexp(t, `^func[0-9]+$`, callingMethod.AsString())
+		assert.Equal(t, attribute.NewSet(
+			attribute.String("service_name", "timedOTel"),
+			attribute.String("calling_method", callingMethod.AsString()),
+			attribute.String("method", "TimedMethod2"),
+			attribute.String("result", "failure"),
+		), histogram.DataPoints[0].Attributes)
+	})
+
+	t.Run("cardinality_limit", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedLimited")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedLimited", "for testing",
+			WithRegisterer(registry), WithCardinalityLimit(1))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, _ = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+		_, _ = (&metricsTestSampleStruct{}).TimedMethod2(context.Background())
+
+		// then
+		families, err := registry.Gather()
+		require.Nil(t, err)
+		methods := make([]string, 0)
+		results := make([]string, 0)
+		for _, family := range families {
+			if family.GetName() == "testTimedLimited_quantiles" {
+				for _, metric := range family.Metric {
+					assert.True(t, doesLabelMatch(metric, serviceNameKey, "timedLimited"))
+					methods = append(methods, getLabel(metric, methodNameKey).GetValue())
+					results = append(results, getLabel(metric, resultKey).GetValue())
+				}
+			}
+		}
+		assert.ElementsMatch(t, []string{"TimedMethod1", metrics.OverflowLabelValue}, methods)
+		assert.ElementsMatch(t, []string{resultSuccess, resultFailure}, results)
+		dropped := gatherSingleMetric(t, registry, metrics.DroppedLabelsMetric)
+		assert.True(t, doesLabelMatch(dropped, "metric", "testTimedLimited"))
+		assert.Equal(t, float64(1), dropped.Counter.GetValue())
+	})
+
+	t.Run("baggage_label_collision", func(t *testing.T) {
+		for _, keys := range [][]string{{"tenant-id", "tenant_id"}, {"result"}, {"service-name"}} {
+			// when
+			advice, err := NewTimedFuncAdviceWithOptions("testTimedBaggageCollision", "for testing",
+				WithRegisterer(prometheus.NewRegistry()), WithBaggageLabels(keys...))
+
+			// then
+			assert.NotNil(t, err, keys)
+			assert.Nil(t, advice, keys)
+		}
+	})
+
+	t.Run("baggage_labels", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedBaggage")
+		tracing.SetBaggageKeys("tenant-id")
+		defer tracing.SetBaggageKeys()
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedBaggage", "for testing",
+			WithRegisterer(registry), WithBaggageLabels("tenant-id", "user"))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		ctx, err := tracing.SetBaggage(context.Background(), "tenant-id", "acme")
+		require.Nil(t, err)
+		ctx, err = tracing.SetBaggage(ctx, "user", "jane")
+		require.Nil(t, err)
+
+		// when
+		_, _ = (&metricsTestSampleStruct{}).TimedMethod1(ctx)
+
+		// then
+		metric := gatherSingleMetric(t, registry, "testTimedBaggage_quantiles")
+		assert.True(t, doesLabelMatch(metric, "tenant_id", "acme"))
+		// user isn't on the allow-list
+		assert.True(t, doesLabelMatch(metric, "user", ""))
+	})
+
+	t.Run("registration_failure", func(t *testing.T) {
+		// given
+		InitAOP("timedConflict")
+		conflicting := prometheus.NewSummaryVec(prometheus.SummaryOpts{Name: "testTimedConflict_quantiles", Help: "other"},
+			[]string{"other"})
+		require.Nil(t, prometheus.Register(conflicting))
+		defer prometheus.Unregister(conflicting)
+
+		// when
+		advice := NewTimedFuncAdvice("testTimedConflict", "for testing")
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// then
+		require.NotNil(t, advice)
+		assert.NotPanics(t, func() {
+			_, _ = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+		})
+	})
+
+	t.Run("prometheus_backend_name", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedPrometheusBackend")
+
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedPrometheusBackend", "for testing",
+			WithBackend(metrics.NewPrometheusBackend(registry)))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*TimedMethod\\d"), advice)
+
+		// when
+		_, _ = (&metricsTestSampleStruct{}).TimedMethod1(context.Background())
+
+		// then the series is named as it is without a backend
+		metric := gatherSingleMetric(t, registry, "testTimedPrometheusBackend_quantiles")
+		assert.Equal(t, uint64(1), metric.Summary.GetSampleCount())
+	})
+
+	t.Run("reuse_registered", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		InitAOP("timedReuse")
+
+		first, err := NewTimedFuncAdviceWithOptions("testTimedReuse", "for testing", WithRegisterer(registry))
+		require.Nil(t, err)
+
+		// when
+		second, err := NewTimedFuncAdviceWithOptions("testTimedReuse", "for testing", WithRegisterer(registry))
+
+		// then
+		require.Nil(t, err)
+		assert.Equal(t, first.(*timedFuncAdvice).timer, second.(*timedFuncAdvice).timer)
+	})
+
+	t.Run("registration_error", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		_, err := NewTimedFuncAdviceWithOptions("testTimedConflict", "for testing", WithRegisterer(registry),
+			WithConstLabels(prometheus.Labels{"team": "core"}))
+		require.Nil(t, err)
+
+		// when
+		advice, err := NewTimedFuncAdviceWithOptions("testTimedConflict", "for testing", WithRegisterer(registry))
+
+		// then
+		assert.NotNil(t, err)
+		assert.Nil(t, advice)
+	})
+}
+
+func gatherSingleMetric(t *testing.T, gatherer prometheus.Gatherer, metricName string) *io_prometheus_client.Metric {
+	metrics, err := gatherer.Gather()
+	require.Nil(t, err)
+
+	for _, metricFamily := range metrics {
+		if metricFamily.GetName() == metricName {
+			require.Equal(t, 1, len(metricFamily.Metric))
+			return metricFamily.Metric[0]
+		}
+	}
+
+	require.Fail(t, "metric not found", metricName)
+	return nil
+}
+
+// steppingClock moves forward by step every time it's read
+type steppingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
 type metricsTestSampleStruct struct {
 	collector		*aspectCollector
 }
@@ -0,0 +1,69 @@
+package aop
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+)
+
+const (
+	profilingPointcutKey = "pointcut"
+)
+
+type profilingCtxKey struct{}
+
+var profilingParentCtxKey = profilingCtxKey{}
+
+// setGoroutineLabels applies the labels of the context to the running goroutine, replaced in tests to see which
+// labels are active
+var setGoroutineLabels = pprof.SetGoroutineLabels
+
+// NewProfilingAdvice creates a new Advice that labels the running goroutine with the service name, method and
+// pointcut so CPU profiles can be broken down by business method
+func NewProfilingAdvice() Advice {
+	return &profilingAdvice{}
+}
+
+type profilingAdvice struct {
+}
+
+func (p *profilingAdvice) Before(ctx context.Context) context.Context {
+	aop := AspectFromContext(ctx)
+	if aop == nil {
+		return ctx
+	}
+
+	labels := pprof.Labels(serviceNameKey, GetServiceName(),
+		methodNameKey, aop.MethodName,
+		profilingPointcutKey, p.pointcutName(aop))
+
+	// remember the context holding the labels in place before we ran so we can restore them after
+	labelledCtx := pprof.WithLabels(context.WithValue(ctx, profilingParentCtxKey, ctx), labels)
+	setGoroutineLabels(labelledCtx)
+
+	return labelledCtx
+}
+
+func (p *profilingAdvice) After(ctx context.Context, err error) {
+	ctxVal := ctx.Value(profilingParentCtxKey)
+	if ctxVal == nil {
+		return
+	}
+
+	if parentCtx, ok := ctxVal.(context.Context); ok {
+		setGoroutineLabels(parentCtx)
+	}
+}
+
+// pointcutName finds the pointcut this advice was registered against for the current aspect
+func (p *profilingAdvice) pointcutName(aop *Aspect) string {
+	for _, jp := range aop.joinPoints {
+		if jp.advice == Advice(p) {
+			if s, ok := jp.pointcut.(fmt.Stringer); ok {
+				return s.String()
+			}
+			return fmt.Sprintf("%T", jp.pointcut)
+		}
+	}
+	return UnknownMethod
+}
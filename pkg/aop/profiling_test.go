@@ -0,0 +1,94 @@
+package aop
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfilingAdvice(t *testing.T) {
+	t.Run("labels_applied", func(t *testing.T) {
+		// given
+		serviceName := "profilingService"
+		InitAOP(serviceName)
+
+		capture := &labelCapturingAspect{}
+		RegisterJoinPoint(NewRegexPointcut(".*ProfiledMethod\\d"), NewProfilingAdvice())
+		RegisterJoinPoint(NewRegexPointcut(".*ProfiledMethod\\d"), capture)
+
+		tStruct := profilingTestSampleStruct{}
+
+		// when
+		err := tStruct.ProfiledMethod1(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{
+			serviceNameKey:       serviceName,
+			methodNameKey:        "github.com/jfbramlett/go-aop/pkg/aop.(*profilingTestSampleStruct).ProfiledMethod1",
+			profilingPointcutKey: ".*ProfiledMethod\\d",
+		}, capture.labels)
+	})
+
+	t.Run("labels_restored", func(t *testing.T) {
+		// given
+		InitAOP("profilingRestore")
+
+		advice := NewProfilingAdvice()
+		RegisterJoinPoint(NewRegexPointcut(".*ProfiledMethod\\d"), advice)
+
+		parentCtx := pprof.WithLabels(context.Background(), pprof.Labels("request", "parent"))
+		ctx := globalAspectMgr.Before(parentCtx, "ProfiledMethod1")
+
+		var active context.Context
+		setGoroutineLabels = func(ctx context.Context) { active = ctx }
+		defer func() { setGoroutineLabels = pprof.SetGoroutineLabels }()
+
+		// when
+		advice.After(ctx, nil)
+
+		// then
+		require.NotNil(t, active)
+		assert.Equal(t, labelsOf(parentCtx), labelsOf(active))
+		_, found := pprof.Label(active, methodNameKey)
+		assert.False(t, found)
+	})
+}
+
+func labelsOf(ctx context.Context) map[string]string {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	return labels
+}
+
+type profilingTestSampleStruct struct {
+}
+
+func (s *profilingTestSampleStruct) ProfiledMethod1(ctx context.Context) (err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	return nil
+}
+
+type labelCapturingAspect struct {
+	labels map[string]string
+}
+
+func (l *labelCapturingAspect) Before(ctx context.Context) context.Context {
+	l.labels = make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		l.labels[key] = value
+		return true
+	})
+	return ctx
+}
+
+func (l *labelCapturingAspect) After(ctx context.Context, err error) {
+}
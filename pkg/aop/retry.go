@@ -0,0 +1,93 @@
+package aop
+
+import (
+	"context"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/stackutils"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 100 * time.Millisecond
+)
+
+type retryCtxKey struct{}
+
+var retryPolicyCtxKey = retryCtxKey{}
+
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// retryScope is the policy of the advised method, only that method's work is retried and not that of the methods
+// it calls (advised or not)
+type retryScope struct {
+	aspect *Aspect
+	policy *retryPolicy
+}
+
+// NewRetryAdvice creates a new Advice that retries the work an advised method runs through Retry. The work is run
+// at most attempts times, waiting backoff before the first retry and doubling the wait for each one after. As an
+// advice can't re-run the method itself the method opts in by wrapping its work:
+//
+//	func (s *MyStruct) MyMethod(ctx context.Context) (err error) {
+//		defer aop.Invoke(&ctx)(&err)
+//		return aop.Retry(ctx, func(ctx context.Context) error {
+//			...
+//		})
+//	}
+func NewRetryAdvice(attempts int, backoff time.Duration) Advice {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &retryAdvice{policy: &retryPolicy{attempts: attempts, backoff: backoff}}
+}
+
+type retryAdvice struct {
+	policy *retryPolicy
+}
+
+func (r *retryAdvice) Before(ctx context.Context) context.Context {
+	aop := AspectFromContext(ctx)
+	if aop == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, retryPolicyCtxKey, &retryScope{aspect: aop, policy: r.policy})
+}
+
+func (r *retryAdvice) After(ctx context.Context, err error) {
+}
+
+// Retry runs the work, retrying it if it fails and the calling method has a retry advice. It must be called from
+// the advised method itself (or a closure in it), calls from methods it calls aren't retried as the policy isn't
+// theirs. It gives up early with the context's error if the context is done while waiting to retry
+func Retry(ctx context.Context, work func(ctx context.Context) error) error {
+	scope, ok := ctx.Value(retryPolicyCtxKey).(*retryScope)
+	if !ok || scope.aspect != AspectFromContext(ctx) {
+		return work(ctx)
+	}
+	// an unadvised method called from the advised one sees the same aspect, so check who is asking
+	if stackutils.EnclosingMethodName(stackutils.CallerName(1)) != scope.aspect.MethodName {
+		return work(ctx)
+	}
+
+	policy := scope.policy
+	backoff := policy.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = work(ctx); err == nil || attempt >= policy.attempts {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
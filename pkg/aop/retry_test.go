@@ -0,0 +1,132 @@
+package aop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryAdvice(t *testing.T) {
+	t.Run("retried_until_success", func(t *testing.T) {
+		// given
+		InitAOP("retrySuccess")
+		RegisterJoinPoint(NewRegexPointcut(".*RetriedMethod$"), NewRetryAdvice(3, time.Millisecond))
+		tStruct := &retryTestSampleStruct{failures: 2}
+
+		// when
+		err := tStruct.RetriedMethod(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, 3, tStruct.calls)
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		// given
+		InitAOP("retryGivesUp")
+		RegisterJoinPoint(NewRegexPointcut(".*RetriedMethod$"), NewRetryAdvice(2, time.Millisecond))
+		tStruct := &retryTestSampleStruct{failures: 5}
+
+		// when
+		err := tStruct.RetriedMethod(context.Background())
+
+		// then
+		assert.Equal(t, errRetryTest, err)
+		assert.Equal(t, 2, tStruct.calls)
+	})
+
+	t.Run("context_done", func(t *testing.T) {
+		// given
+		InitAOP("retryCancelled")
+		RegisterJoinPoint(NewRegexPointcut(".*RetriedMethod$"), NewRetryAdvice(3, time.Hour))
+		tStruct := &retryTestSampleStruct{failures: 5}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// when
+		err := tStruct.RetriedMethod(ctx)
+
+		// then
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, 1, tStruct.calls)
+	})
+
+	t.Run("not_advised", func(t *testing.T) {
+		// given
+		InitAOP("retryNotAdvised")
+		RegisterJoinPoint(NewRegexPointcut(".*CallsUnretriedMethod$"), NewRetryAdvice(3, time.Millisecond))
+		RegisterJoinPoint(NewRegexPointcut(".*\\.UnretriedMethod$"), NewSpanFuncAdvice())
+		tStruct := &retryTestSampleStruct{failures: 5}
+
+		// when
+		err := tStruct.CallsUnretriedMethod(context.Background())
+
+		// then only the outer method's work is retried, not the call it makes
+		assert.Equal(t, errRetryTest, err)
+		assert.Equal(t, 1, tStruct.calls)
+	})
+
+	t.Run("unadvised_callee", func(t *testing.T) {
+		// given
+		InitAOP("retryUnadvisedCallee")
+		RegisterJoinPoint(NewRegexPointcut(".*CallsPlainMethod$"), NewRetryAdvice(3, time.Millisecond))
+		tStruct := &retryTestSampleStruct{failures: 5}
+
+		// when
+		err := tStruct.CallsPlainMethod(context.Background())
+
+		// then the callee's work isn't retried with the caller's policy
+		assert.Equal(t, errRetryTest, err)
+		assert.Equal(t, 1, tStruct.calls)
+	})
+}
+
+var errRetryTest = errors.New("retry test failure")
+
+type retryTestSampleStruct struct {
+	failures int
+	calls    int
+}
+
+func (s *retryTestSampleStruct) RetriedMethod(ctx context.Context) (err error) {
+	defer Invoke(&ctx)(&err)
+
+	return Retry(ctx, func(ctx context.Context) error {
+		s.calls++
+		if s.calls <= s.failures {
+			return errRetryTest
+		}
+		return nil
+	})
+}
+
+func (s *retryTestSampleStruct) CallsUnretriedMethod(ctx context.Context) (err error) {
+	defer Invoke(&ctx)(&err)
+
+	return s.UnretriedMethod(ctx)
+}
+
+func (s *retryTestSampleStruct) UnretriedMethod(ctx context.Context) (err error) {
+	defer Invoke(&ctx)(&err)
+
+	return Retry(ctx, func(ctx context.Context) error {
+		s.calls++
+		return errRetryTest
+	})
+}
+
+func (s *retryTestSampleStruct) CallsPlainMethod(ctx context.Context) (err error) {
+	defer Invoke(&ctx)(&err)
+
+	return s.PlainMethod(ctx)
+}
+
+func (s *retryTestSampleStruct) PlainMethod(ctx context.Context) error {
+	return Retry(ctx, func(ctx context.Context) error {
+		s.calls++
+		return errRetryTest
+	})
+}
@@ -0,0 +1,138 @@
+package aop
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/jfbramlett/go-aop/pkg/tracing"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	slowCallEvent        = "slow_call"
+	slowCallStackSize    = 16 * 1024
+	durationKey          = "duration"
+	thresholdKey         = "threshold"
+	argsKey              = "args"
+	callChainKey         = "call_chain"
+	stackKey             = "stack"
+	eventKey             = "event"
+	defaultSlowCallLimit = 1
+)
+
+type slowCallCtxKey struct{}
+
+var slowCallStartCtxKey = slowCallCtxKey{}
+
+// SlowCallOption is used to configure the behavior of a slow call advice
+type SlowCallOption func(s *slowCallAdvice)
+
+// WithSlowCallRateLimit limits the number of slow call warnings logged to limit per interval
+func WithSlowCallRateLimit(limit int, interval time.Duration) SlowCallOption {
+	return func(s *slowCallAdvice) {
+		s.limiter = newRateLimiter(limit, interval)
+	}
+}
+
+// NewSlowCallAdvice creates a new Advice that logs a warning (and adds a span event) whenever a method takes longer
+// than the given threshold, by default at most one warning is logged per second
+func NewSlowCallAdvice(threshold time.Duration, opts ...SlowCallOption) Advice {
+	s := &slowCallAdvice{threshold: threshold, limiter: newRateLimiter(defaultSlowCallLimit, time.Second)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type slowCallAdvice struct {
+	threshold time.Duration
+	limiter   *rateLimiter
+}
+
+func (s *slowCallAdvice) Before(ctx context.Context) context.Context {
+	aop := AspectFromContext(ctx)
+	if aop == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, slowCallStartCtxKey, clock.Now())
+}
+
+func (s *slowCallAdvice) After(ctx context.Context, err error) {
+	aop := AspectFromContext(ctx)
+	if aop == nil {
+		return
+	}
+
+	ctxVal := ctx.Value(slowCallStartCtxKey)
+	if ctxVal == nil {
+		return
+	}
+	start, ok := ctxVal.(time.Time)
+	if !ok {
+		return
+	}
+
+	elapsed := since(start)
+	if elapsed <= s.threshold {
+		return
+	}
+
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		span.LogFields(otlog.String(eventKey, slowCallEvent),
+			otlog.String(durationKey, elapsed.String()),
+			otlog.String(thresholdKey, s.threshold.String()))
+	}
+
+	if !s.limiter.Allow() {
+		return
+	}
+
+	stack := make([]byte, slowCallStackSize)
+	stack = stack[:runtime.Stack(stack, false)]
+
+	logger, _ := logging.LoggerFromContext(ctx)
+	logger.WithFields(logrus.Fields{
+		methodNameKey: aop.MethodName,
+		durationKey:   elapsed.String(),
+		thresholdKey:  s.threshold.String(),
+		argsKey:       ArgsFromContext(ctx),
+		callChainKey:  AspectChainFromContext(ctx),
+		stackKey:      string(stack),
+	}).Warn("slow call")
+}
+
+// rateLimiter allows up to limit events within each interval
+type rateLimiter struct {
+	limit       int
+	interval    time.Duration
+	mux         sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+func newRateLimiter(limit int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, interval: interval}
+}
+
+// Allow reports whether another event may happen in the current interval
+func (r *rateLimiter) Allow() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := clock.Now()
+	if now.Sub(r.windowStart) >= r.interval {
+		r.windowStart = now
+		r.count = 0
+	}
+
+	if r.count >= r.limit {
+		return false
+	}
+	r.count++
+	return true
+}
@@ -0,0 +1,168 @@
+package aop
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowCallAdvice(t *testing.T) {
+	t.Run("slow_call_logged", func(t *testing.T) {
+		// given
+		logger, hook := logtest.NewNullLogger()
+		ctx := logging.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+
+		InitAOP("slowCallLogged")
+		RegisterJoinPoint(NewRegexPointcut(".*SlowMethod\\d"), NewSlowCallAdvice(time.Millisecond))
+
+		tStruct := slowCallTestSampleStruct{}
+
+		// when
+		err := tStruct.SlowMethod1(ctx, "arg1", 1)
+
+		// then
+		assert.Nil(t, err)
+		require.Equal(t, 1, len(hook.AllEntries()))
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, []interface{}{"arg1", 1}, entry.Data[argsKey])
+		assert.Equal(t, []string{"github.com/jfbramlett/go-aop/pkg/aop.(*slowCallTestSampleStruct).SlowMethod1"},
+			entry.Data[callChainKey])
+		assert.Contains(t, entry.Data[stackKey], "SlowMethod1")
+	})
+
+	t.Run("fast_call_ignored", func(t *testing.T) {
+		// given
+		logger, hook := logtest.NewNullLogger()
+		ctx := logging.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+
+		InitAOP("slowCallIgnored")
+		RegisterJoinPoint(NewRegexPointcut(".*SlowMethod\\d"), NewSlowCallAdvice(time.Hour))
+
+		tStruct := slowCallTestSampleStruct{}
+
+		// when
+		err := tStruct.SlowMethod1(ctx, "arg1", 1)
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(hook.AllEntries()))
+	})
+
+	t.Run("nested_call_chain", func(t *testing.T) {
+		// given
+		logger, hook := logtest.NewNullLogger()
+		ctx := logging.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+
+		InitAOP("slowCallNested")
+		RegisterJoinPoint(NewRegexPointcut(".*SlowMethod\\d"), NewSlowCallAdvice(time.Millisecond,
+			WithSlowCallRateLimit(10, time.Minute)))
+
+		tStruct := slowCallTestSampleStruct{}
+
+		// when
+		err := tStruct.SlowMethod2(ctx)
+
+		// then
+		assert.Nil(t, err)
+		require.Equal(t, 2, len(hook.AllEntries()))
+		assert.Equal(t, []string{"github.com/jfbramlett/go-aop/pkg/aop.(*slowCallTestSampleStruct).SlowMethod2",
+			"github.com/jfbramlett/go-aop/pkg/aop.(*slowCallTestSampleStruct).SlowMethod1"},
+			hook.AllEntries()[0].Data[callChainKey])
+	})
+
+	t.Run("nested_args", func(t *testing.T) {
+		// given
+		logger, hook := logtest.NewNullLogger()
+		ctx := logging.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+
+		InitAOP("slowCallNestedArgs")
+		RegisterJoinPoint(NewRegexPointcut(".*SlowMethod\\d"), NewSlowCallAdvice(time.Millisecond,
+			WithSlowCallRateLimit(10, time.Minute)))
+
+		tStruct := slowCallTestSampleStruct{}
+
+		// when
+		err := tStruct.SlowMethod3(ctx, "secret")
+
+		// then
+		assert.Nil(t, err)
+		require.Equal(t, 2, len(hook.AllEntries()))
+		inner, outer := hook.AllEntries()[0], hook.AllEntries()[1]
+		assert.Equal(t, "github.com/jfbramlett/go-aop/pkg/aop.(*slowCallTestSampleStruct).SlowMethod4", inner.Data[methodNameKey])
+		assert.Empty(t, inner.Data[argsKey])
+		assert.Equal(t, []interface{}{"secret"}, outer.Data[argsKey])
+	})
+
+	t.Run("rate_limited", func(t *testing.T) {
+		// given
+		mockTracer := &mocktracer.MockTracer{}
+		opentracing.SetGlobalTracer(mockTracer)
+
+		logger, hook := logtest.NewNullLogger()
+		ctx := logging.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+
+		InitAOP("slowCallRateLimited")
+		RegisterJoinPoint(NewRegexPointcut(".*SlowMethod\\d"), NewSpanFuncAdvice())
+		RegisterJoinPoint(NewRegexPointcut(".*SlowMethod\\d"), NewSlowCallAdvice(time.Millisecond,
+			WithSlowCallRateLimit(1, time.Minute)))
+
+		tStruct := slowCallTestSampleStruct{}
+
+		// when
+		_ = tStruct.SlowMethod1(ctx, "arg1", 1)
+		_ = tStruct.SlowMethod1(ctx, "arg2", 2)
+
+		// then
+		assert.Equal(t, 1, len(hook.AllEntries()))
+		finishedSpans := mockTracer.FinishedSpans()
+		require.Equal(t, 2, len(finishedSpans))
+		for _, span := range finishedSpans {
+			require.Equal(t, 1, len(span.Logs()))
+			assert.Equal(t, slowCallEvent, span.Logs()[0].Fields[0].ValueString)
+		}
+	})
+}
+
+type slowCallTestSampleStruct struct {
+}
+
+func (s *slowCallTestSampleStruct) SlowMethod1(ctx context.Context, arg1 string, arg2 int) (err error) {
+	ctx = Before(ContextWithArgs(ctx, arg1, arg2))
+	defer func() { After(ctx, err) }()
+
+	time.Sleep(5 * time.Millisecond)
+
+	return nil
+}
+
+func (s *slowCallTestSampleStruct) SlowMethod2(ctx context.Context) (err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	return s.SlowMethod1(ctx, "nested", 2)
+}
+
+func (s *slowCallTestSampleStruct) SlowMethod3(ctx context.Context, secret string) (err error) {
+	ctx = Before(ContextWithArgs(ctx, secret))
+	defer func() { After(ctx, err) }()
+
+	return s.SlowMethod4(ctx)
+}
+
+func (s *slowCallTestSampleStruct) SlowMethod4(ctx context.Context) (err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	time.Sleep(5 * time.Millisecond)
+
+	return nil
+}
@@ -2,18 +2,61 @@ package aop
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+
 	"github.com/jfbramlett/go-aop/pkg/stackutils"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
 
 	"github.com/jfbramlett/go-aop/pkg/tracing"
 )
 
+const (
+	argTagPrefix = "arg."
+
+	logEventError   = "error"
+	logErrorKind    = "error.kind"
+	logErrorMessage = "message"
+	logErrorStack   = "stack"
+)
+
+// SpanFuncOption is used to configure the spans created by a span advice
+type SpanFuncOption func(s *spanAdvice)
+
+// WithSpanArgs records the arguments captured with ContextWithArgs as span tags (arg.0, arg.1, ...) for methods
+// matching the pointcut, only the given positions are recorded or all of them if none are given. Arguments are
+// only recorded for methods that are allowed this way as they may hold sensitive data, and only those the method
+// captured itself (never those of the method that called it)
+func WithSpanArgs(pointcut Pointcut, positions ...int) SpanFuncOption {
+	return func(s *spanAdvice) {
+		s.allowedArgs = append(s.allowedArgs, allowedArgs{pointcut: pointcut, positions: positions})
+	}
+}
+
+type allowedArgs struct {
+	pointcut  Pointcut
+	positions []int
+}
+
 // NewSpanFuncAdvice creates a new Advice used to wrap something as a new OpenTracing span
 func NewSpanFuncAdvice() Advice {
 	return &spanAdvice{}
 }
 
+// NewSpanFuncAdviceWithOptions is the same as NewSpanFuncAdvice but configured with the given options
+func NewSpanFuncAdviceWithOptions(opts ...SpanFuncOption) Advice {
+	advice := &spanAdvice{}
+	for _, opt := range opts {
+		opt(advice)
+	}
+	return advice
+}
+
 type spanAdvice struct {
+	allowedArgs []allowedArgs
 }
 
 func (s *spanAdvice) Before(ctx context.Context) context.Context {
@@ -57,5 +100,56 @@ func (s *spanAdvice) After(ctx context.Context, err error) {
 	span.SetTag(methodNameKey, stackutils.MethodNameFromFullPath(aop.MethodName))
 	span.SetTag(resultKey, result)
 
+	if err != nil {
+		s.recordError(span, err)
+	}
+	s.recordArgs(ctx, span, aop.MethodName)
+
 	span.Finish()
 }
+
+// recordError marks the span as failed and logs the error following the OpenTracing semantic conventions
+func (s *spanAdvice) recordError(span opentracing.Span, err error) {
+	ext.Error.Set(span, true)
+
+	fields := []log.Field{
+		log.String("event", logEventError),
+		log.Error(err),
+		log.String(logErrorKind, fmt.Sprintf("%T", err)),
+		log.String(logErrorMessage, err.Error()),
+	}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		fields[2] = log.String(logErrorKind, fmt.Sprintf("panic(%T)", panicErr.Value))
+		fields = append(fields, log.String(logErrorStack, string(panicErr.Stack)))
+	}
+	span.LogFields(fields...)
+}
+
+func (s *spanAdvice) recordArgs(ctx context.Context, span opentracing.Span, method string) {
+	if len(s.allowedArgs) == 0 {
+		return
+	}
+	args := ArgsFromContext(ctx)
+	if len(args) == 0 {
+		return
+	}
+
+	for _, allowed := range s.allowedArgs {
+		if !allowed.pointcut.Matches(method) {
+			continue
+		}
+
+		if len(allowed.positions) == 0 {
+			for i, arg := range args {
+				span.SetTag(argTagPrefix+strconv.Itoa(i), fmt.Sprintf("%v", arg))
+			}
+			continue
+		}
+		for _, i := range allowed.positions {
+			if i >= 0 && i < len(args) {
+				span.SetTag(argTagPrefix+strconv.Itoa(i), fmt.Sprintf("%v", args[i]))
+			}
+		}
+	}
+}
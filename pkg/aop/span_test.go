@@ -3,8 +3,10 @@ package aop
 import (
     "context"
     "github.com/opentracing/opentracing-go"
+    "github.com/opentracing/opentracing-go/ext"
     "github.com/opentracing/opentracing-go/mocktracer"
     "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
     "testing"
     "time"
 )
@@ -113,6 +115,206 @@ func TestSpanFuncAdvice(t *testing.T) {
         }, startTime, finishTime)
 
     })
+
+    t.Run("run_error_details", func(t *testing.T) {
+        // given
+        mockTracer := &mocktracer.MockTracer{}
+        opentracing.SetGlobalTracer(mockTracer)
+
+        InitAOP("spanFuncErrorDetails")
+
+        RegisterJoinPoint(NewRegexPointcut(".*SpanMethod\\d"), NewSpanFuncAdvice())
+
+        tStruct := metricsTestSampleStruct{}
+
+        // when
+        _, err := tStruct.SpanMethod2(context.Background())
+
+        // then
+        assert.NotNil(t, err)
+
+        finishedSpans := mockTracer.FinishedSpans()
+        require.Equal(t, 1, len(finishedSpans))
+        assert.Equal(t, true, finishedSpans[0].Tag(string(ext.Error)))
+        require.Equal(t, 1, len(finishedSpans[0].Logs()))
+        fields := logFields(finishedSpans[0].Logs()[0])
+        assert.Equal(t, logEventError, fields["event"])
+        assert.Equal(t, "*errors.errorString", fields[logErrorKind])
+        assert.Equal(t, "failed", fields[logErrorMessage])
+        assert.Equal(t, "failed", fields["error.object"])
+    })
+
+    t.Run("run_success_no_error_details", func(t *testing.T) {
+        // given
+        mockTracer := &mocktracer.MockTracer{}
+        opentracing.SetGlobalTracer(mockTracer)
+
+        InitAOP("spanFuncNoErrorDetails")
+
+        RegisterJoinPoint(NewRegexPointcut(".*SpanMethod\\d"), NewSpanFuncAdvice())
+
+        tStruct := metricsTestSampleStruct{}
+
+        // when
+        _, err := tStruct.SpanMethod1(context.Background())
+
+        // then
+        assert.Nil(t, err)
+
+        finishedSpans := mockTracer.FinishedSpans()
+        require.Equal(t, 1, len(finishedSpans))
+        assert.Nil(t, finishedSpans[0].Tag(string(ext.Error)))
+        assert.Equal(t, 0, len(finishedSpans[0].Logs()))
+    })
+
+    t.Run("run_panic", func(t *testing.T) {
+        // given
+        mockTracer := &mocktracer.MockTracer{}
+        opentracing.SetGlobalTracer(mockTracer)
+
+        InitAOP("spanFuncPanic")
+
+        RegisterJoinPoint(NewRegexPointcut(".*SpanPanicMethod$"), NewSpanFuncAdvice())
+
+        tStruct := spanTestSampleStruct{}
+
+        // when
+        assert.Panics(t, func() { _ = tStruct.SpanPanicMethod(context.Background()) })
+
+        // then
+        finishedSpans := mockTracer.FinishedSpans()
+        require.Equal(t, 1, len(finishedSpans))
+        assert.Equal(t, resultFailure, finishedSpans[0].Tag(resultKey))
+        assert.Equal(t, true, finishedSpans[0].Tag(string(ext.Error)))
+        require.Equal(t, 1, len(finishedSpans[0].Logs()))
+        fields := logFields(finishedSpans[0].Logs()[0])
+        assert.Equal(t, "panic(string)", fields[logErrorKind])
+        assert.Equal(t, "panic: boom", fields[logErrorMessage])
+        assert.Contains(t, fields[logErrorStack], "SpanPanicMethod")
+    })
+
+    t.Run("run_args", func(t *testing.T) {
+        // given
+        mockTracer := &mocktracer.MockTracer{}
+        opentracing.SetGlobalTracer(mockTracer)
+
+        InitAOP("spanFuncArgs")
+
+        RegisterJoinPoint(NewRegexPointcut(".*SpanArgsMethod\\d$"),
+            NewSpanFuncAdviceWithOptions(WithSpanArgs(NewRegexPointcut(".*SpanArgsMethod1$"), 0)))
+
+        tStruct := spanTestSampleStruct{}
+
+        // when
+        err := tStruct.SpanArgsMethod2(context.Background(), "order-1", "secret")
+
+        // then
+        assert.Nil(t, err)
+
+        finishedSpans := mockTracer.FinishedSpans()
+        require.Equal(t, 2, len(finishedSpans))
+        // SpanArgsMethod1 is allowed to record its first argument only
+        assert.Equal(t, "order-1", finishedSpans[0].Tag(argTagPrefix+"0"))
+        assert.Nil(t, finishedSpans[0].Tag(argTagPrefix+"1"))
+        // SpanArgsMethod2 isn't allowed to record any
+        assert.Nil(t, finishedSpans[1].Tag(argTagPrefix+"0"))
+        assert.Nil(t, finishedSpans[1].Tag(argTagPrefix+"1"))
+    })
+
+    t.Run("run_all_args", func(t *testing.T) {
+        // given
+        mockTracer := &mocktracer.MockTracer{}
+        opentracing.SetGlobalTracer(mockTracer)
+
+        InitAOP("spanFuncAllArgs")
+
+        RegisterJoinPoint(NewRegexPointcut(".*SpanArgsMethod1$"),
+            NewSpanFuncAdviceWithOptions(WithSpanArgs(NewRegexPointcut(".*"))))
+
+        tStruct := spanTestSampleStruct{}
+
+        // when
+        err := tStruct.SpanArgsMethod1(context.Background(), "order-1", "secret")
+
+        // then
+        assert.Nil(t, err)
+
+        finishedSpans := mockTracer.FinishedSpans()
+        require.Equal(t, 1, len(finishedSpans))
+        assert.Equal(t, "order-1", finishedSpans[0].Tag(argTagPrefix+"0"))
+        assert.Equal(t, "secret", finishedSpans[0].Tag(argTagPrefix+"1"))
+    })
+
+    t.Run("nested_args", func(t *testing.T) {
+        // the inner method is safe-listed but has no args of its own, whether or not the outer method is advised
+        for _, pointcut := range []string{".*SpanArgsMethod[34]$", ".*SpanArgsMethod3$"} {
+            // given
+            mockTracer := &mocktracer.MockTracer{}
+            opentracing.SetGlobalTracer(mockTracer)
+
+            InitAOP("spanFuncNestedArgs")
+
+            RegisterJoinPoint(NewRegexPointcut(pointcut),
+                NewSpanFuncAdviceWithOptions(WithSpanArgs(NewRegexPointcut(".*SpanArgsMethod3$"))))
+
+            tStruct := spanTestSampleStruct{}
+
+            // when
+            err := tStruct.SpanArgsMethod4(context.Background(), "secret")
+
+            // then
+            assert.Nil(t, err)
+
+            finishedSpans := mockTracer.FinishedSpans()
+            require.NotEmpty(t, finishedSpans, pointcut)
+            assert.Equal(t, "spanTestSampleStruct.SpanArgsMethod3", finishedSpans[0].OperationName, pointcut)
+            assert.Nil(t, finishedSpans[0].Tag(argTagPrefix+"0"), pointcut)
+        }
+    })
+}
+
+type spanTestSampleStruct struct {
+}
+
+func (s *spanTestSampleStruct) SpanArgsMethod1(ctx context.Context, orderID string, secret string) (err error) {
+    ctx = ContextWithArgs(ctx, orderID, secret)
+    defer Invoke(&ctx)(&err)
+
+    return nil
+}
+
+func (s *spanTestSampleStruct) SpanArgsMethod2(ctx context.Context, orderID string, secret string) (err error) {
+    ctx = ContextWithArgs(ctx, orderID, secret)
+    defer Invoke(&ctx)(&err)
+
+    return s.SpanArgsMethod1(ctx, orderID, secret)
+}
+
+func (s *spanTestSampleStruct) SpanArgsMethod3(ctx context.Context) (err error) {
+    defer Invoke(&ctx)(&err)
+
+    return nil
+}
+
+func (s *spanTestSampleStruct) SpanArgsMethod4(ctx context.Context, secret string) (err error) {
+    ctx = ContextWithArgs(ctx, secret)
+    defer Invoke(&ctx)(&err)
+
+    return s.SpanArgsMethod3(ctx)
+}
+
+func (s *spanTestSampleStruct) SpanPanicMethod(ctx context.Context) (err error) {
+    defer Invoke(&ctx)(&err)
+
+    panic("boom")
+}
+
+func logFields(record mocktracer.MockLogRecord) map[string]string {
+    fields := make(map[string]string, len(record.Fields))
+    for _, field := range record.Fields {
+        fields[field.Key] = field.ValueString
+    }
+    return fields
 }
 
 func validateSpan(t *testing.T, span *mocktracer.MockSpan, expectedOperationName string, tags map[string]string, timeStartAfter time.Time, timeFinishBefore time.Time) {
@@ -0,0 +1,83 @@
+package aop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/jfbramlett/go-aop/pkg/metrics"
+	"github.com/jfbramlett/go-aop/pkg/stackutils"
+)
+
+// NewThroughputFuncAdvice creates a new Advice that tracks the number of in-flight calls along with the number of
+// invocations and errors for each matched method. If the metrics can't be registered the error is logged and the
+// advice records nothing
+func NewThroughputFuncAdvice(name string, description string) Advice {
+	advice, err := NewThroughputFuncAdviceWithBackend(name, description, metrics.NewPrometheusBackend(nil))
+	if err != nil {
+		logger, _ := logging.LoggerFromContext(context.Background())
+		logger.WithError(err).Errorf("failed to create throughput advice %s, throughput will not be recorded", name)
+		return noopAdvice{}
+	}
+	return advice
+}
+
+// NewThroughputFuncAdviceWithBackend is the same as NewThroughputFuncAdvice but records through the given metrics
+// backend, returning an error if the metrics couldn't be created. Wrap the backend with metrics.LimitCardinality (keeping
+// the service_name label) to cap the distinct label combinations recorded
+func NewThroughputFuncAdviceWithBackend(name string, description string, backend metrics.Backend) (Advice, error) {
+	promTags := []string{serviceNameKey, methodNameKey}
+
+	inFlight, err := backend.Gauge(fmt.Sprintf("%v_in_flight", name), fmt.Sprintf("%v (in-flight calls)", description), promTags)
+	if err != nil {
+		return nil, err
+	}
+
+	invocations, err := backend.Counter(fmt.Sprintf("%v_invocations_total", name), fmt.Sprintf("%v (invocations)", description), promTags)
+	if err != nil {
+		return nil, err
+	}
+
+	errors, err := backend.Counter(fmt.Sprintf("%v_errors_total", name), fmt.Sprintf("%v (errors)", description), promTags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &throughputFuncAdvice{inFlight: inFlight, invocations: invocations, errors: errors}, nil
+}
+
+type throughputFuncAdvice struct {
+	inFlight    metrics.Gauge
+	invocations metrics.Counter
+	errors      metrics.Counter
+}
+
+func (t *throughputFuncAdvice) Before(ctx context.Context) context.Context {
+	aop := AspectFromContext(ctx)
+	if aop == nil {
+		return ctx
+	}
+
+	values := t.labelValues(aop)
+	t.invocations.Add(ctx, 1, values...)
+	t.inFlight.Add(ctx, 1, values...)
+
+	return ctx
+}
+
+func (t *throughputFuncAdvice) After(ctx context.Context, err error) {
+	aop := AspectFromContext(ctx)
+	if aop == nil {
+		return
+	}
+
+	values := t.labelValues(aop)
+	t.inFlight.Add(ctx, -1, values...)
+	if err != nil {
+		t.errors.Add(ctx, 1, values...)
+	}
+}
+
+func (t *throughputFuncAdvice) labelValues(aop *Aspect) []string {
+	return []string{GetServiceName(), stackutils.MethodNameFromFullPath(aop.MethodName)}
+}
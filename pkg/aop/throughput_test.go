@@ -0,0 +1,111 @@
+package aop
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jfbramlett/go-aop/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThroughputFuncAdvice(t *testing.T) {
+	t.Run("run_success_and_error", func(t *testing.T) {
+		// given
+		serviceName := "throughputFunc"
+		InitAOP(serviceName)
+
+		registry := prometheus.NewRegistry()
+		metricName := "testThroughputFuncAdvice"
+		advice, err := NewThroughputFuncAdviceWithBackend(metricName, "for testing", metrics.NewPrometheusBackend(registry))
+		require.Nil(t, err)
+		RegisterJoinPoint(NewRegexPointcut(".*ThroughputMethod\\d"), advice)
+
+		tStruct := throughputTestSampleStruct{}
+		inFlight := float64(-1)
+
+		// when
+		err = tStruct.ThroughputMethod1(context.Background(), func() {
+			inFlight = getMetricValue(t, registry, metricName+"_in_flight", serviceName, "ThroughputMethod1")
+		})
+		assert.Nil(t, err)
+		err = tStruct.ThroughputMethod1(context.Background(), func() {})
+		assert.Nil(t, err)
+		err = tStruct.ThroughputMethod2(context.Background())
+		assert.NotNil(t, err)
+
+		// then
+		assert.Equal(t, float64(1), inFlight)
+		assert.Equal(t, float64(0), getMetricValue(t, registry, metricName+"_in_flight", serviceName, "ThroughputMethod1"))
+		assert.Equal(t, float64(2), getMetricValue(t, registry, metricName+"_invocations_total", serviceName, "ThroughputMethod1"))
+		assert.Equal(t, float64(1), getMetricValue(t, registry, metricName+"_invocations_total", serviceName, "ThroughputMethod2"))
+		assert.Equal(t, float64(1), getMetricValue(t, registry, metricName+"_errors_total", serviceName, "ThroughputMethod2"))
+		assert.Equal(t, float64(-1), getMetricValue(t, registry, metricName+"_errors_total", serviceName, "ThroughputMethod1"))
+	})
+
+	t.Run("registration_failure", func(t *testing.T) {
+		// given
+		InitAOP("throughputConflict")
+		conflicting := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "testThroughputConflict_invocations_total", Help: "other"},
+			[]string{"other"})
+		require.Nil(t, prometheus.Register(conflicting))
+		defer prometheus.Unregister(conflicting)
+
+		// when
+		advice := NewThroughputFuncAdvice("testThroughputConflict", "for testing")
+		RegisterJoinPoint(NewRegexPointcut(".*ThroughputMethod\\d"), advice)
+
+		// then
+		require.NotNil(t, advice)
+		assert.NotPanics(t, func() {
+			_ = (&throughputTestSampleStruct{}).ThroughputMethod2(context.Background())
+		})
+	})
+}
+
+type throughputTestSampleStruct struct {
+}
+
+func (s *throughputTestSampleStruct) ThroughputMethod1(ctx context.Context, during func()) (err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	during()
+
+	return nil
+}
+
+func (s *throughputTestSampleStruct) ThroughputMethod2(ctx context.Context) (err error) {
+	ctx = Before(ctx)
+	defer func() { After(ctx, err) }()
+
+	return errors.New("failed")
+}
+
+// getMetricValue gets the value of a gauge or counter for the given service and method (or -1 if not found)
+func getMetricValue(t *testing.T, gatherer prometheus.Gatherer, metricName string, serviceName string, method string) float64 {
+	metricFamilies, err := gatherer.Gather()
+	require.Nil(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != metricName {
+			continue
+		}
+		for _, metric := range getMetricsOfInterest(metricFamily, serviceName) {
+			if doesLabelMatch(metric, methodNameKey, method) {
+				return counterOrGaugeValue(metric)
+			}
+		}
+	}
+	return -1
+}
+
+func counterOrGaugeValue(metric *io_prometheus_client.Metric) float64 {
+	if metric.Counter != nil {
+		return metric.Counter.GetValue()
+	}
+	return metric.Gauge.GetValue()
+}
@@ -33,7 +33,7 @@ func (f *FileSource) Read() map[string]interface{} {
 	if err != nil {
 		return cfg
 	}
-	err = json.Unmarshal(fileContent, cfg)
+	_ = json.Unmarshal(fileContent, &cfg)
 
 	return cfg
 }
@@ -14,8 +14,9 @@ var logKey = &logKeyStruct{}
 func LoggerFromContext(ctx context.Context) (*logrus.Entry, context.Context) {
 	logger := ctx.Value(logKey)
 	if logger == nil {
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-		logger = logrus.New()
+		baseLogger := logrus.New()
+		baseLogger.SetFormatter(&logrus.JSONFormatter{})
+		logger = logrus.NewEntry(baseLogger)
 		ctx = context.WithValue(ctx, logKey, logger)
 	}
 
@@ -1,5 +1,27 @@
 package messaging
 
+import (
+	"context"
+
+	"github.com/jfbramlett/go-aop/pkg/tracing"
+)
+
 type Envelope struct {
-	Content interface{} `json:"content,omitempty"`
+	Content interface{}       `json:"content,omitempty"`
+	Baggage map[string]string `json:"baggage,omitempty"`
+}
+
+// NewEnvelope wraps the content in an envelope carrying the baggage of the context
+func NewEnvelope(ctx context.Context, content interface{}) Envelope {
+	envelope := Envelope{Content: content}
+	if baggage := tracing.Baggage(ctx); len(baggage) > 0 {
+		envelope.Baggage = baggage
+	}
+	return envelope
+}
+
+// Context adds the baggage carried by the envelope to the context, invalid baggage items are skipped and listed in
+// the error but the context still carries the valid ones
+func (e Envelope) Context(ctx context.Context) (context.Context, error) {
+	return tracing.ContextWithBaggage(ctx, e.Baggage)
 }
@@ -1,8 +1,12 @@
 package messaging
 
 import (
+    "context"
     "fmt"
     "github.com/jfbramlett/go-aop/pkg/jsonutils"
+    "github.com/jfbramlett/go-aop/pkg/tracing"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
     "testing"
 )
 
@@ -30,6 +34,49 @@ func TestSerializeDeserialize(t *testing.T) {
 	fmt.Printf("%v", newEnvelope)
 }
 
+func TestEnvelopeBaggage(t *testing.T) {
+	t.Run("round_trip", func(t *testing.T) {
+		// given
+		ctx, err := tracing.ContextWithBaggage(context.Background(), map[string]string{"tenant": "acme", "flag": "new-checkout"})
+		require.Nil(t, err)
+		str, err := jsonutils.ToJSON(NewEnvelope(ctx, &TestPayload{Name: "John", Age: 50}))
+		require.Nil(t, err)
+
+		// when
+		received := Envelope{Content: ContentType()}
+		require.Nil(t, jsonutils.FromJSON(str, &received))
+		receivedCtx, err := received.Context(context.Background())
+
+		// then
+		assert.Nil(t, err)
+		assert.Equal(t, &TestPayload{Name: "John", Age: 50}, received.Content)
+		assert.Equal(t, map[string]string{"tenant": "acme", "flag": "new-checkout"}, tracing.Baggage(receivedCtx))
+	})
+
+	t.Run("no_baggage", func(t *testing.T) {
+		// when
+		str, err := jsonutils.ToJSON(NewEnvelope(context.Background(), &TestPayload{Name: "John", Age: 50}))
+
+		// then
+		require.Nil(t, err)
+		assert.NotContains(t, str, "baggage")
+	})
+
+	t.Run("invalid_baggage", func(t *testing.T) {
+		// given
+		str := `{"content":{"name":"John","age":50},"baggage":{"tenant":"acme","":"no-key"}}`
+
+		// when
+		received := Envelope{Content: ContentType()}
+		require.Nil(t, jsonutils.FromJSON(str, &received))
+		receivedCtx, err := received.Context(context.Background())
+
+		// then
+		assert.NotNil(t, err)
+		assert.Equal(t, map[string]string{"tenant": "acme"}, tracing.Baggage(receivedCtx))
+	})
+}
+
 func ContentType() interface{} {
 	return &TestPayload{}
 }
@@ -41,7 +41,7 @@ type rabbitMQSender struct {
 }
 
 func (r *rabbitMQSender) Publish(ctx context.Context, msg interface{}) error {
-	envelope := messaging.Envelope{Content: msg}
+	envelope := messaging.NewEnvelope(ctx, msg)
 
 	content, err := jsonutils.ToJSON(envelope)
 	if err != nil {
@@ -3,6 +3,7 @@ package rabbitmq
 import (
     "context"
     "github.com/jfbramlett/go-aop/pkg/jsonutils"
+    "github.com/jfbramlett/go-aop/pkg/logging"
 
     "github.com/jfbramlett/go-aop/pkg/messaging"
     "github.com/streadway/amqp"
@@ -64,7 +65,13 @@ func (rr *rabbitMQReceiver) Run() error {
 			if err != nil {
 				continue
 			}
-			err = rr.callback(context.Background(), envelope.Content)
+			// baggage is best effort, the message is delivered even if some of it is invalid
+			ctx, err := envelope.Context(context.Background())
+			if err != nil {
+				logger, _ := logging.LoggerFromContext(ctx)
+				logger.WithError(err).Warn("invalid baggage in message")
+			}
+			err = rr.callback(ctx, envelope.Content)
 			if err != nil {
 				continue
 			}
@@ -10,9 +10,9 @@ const (
 
 type OutboxHandlerFunc func(ctx context.Context, msg *Message) error
 
-// outboxManager is a local interface used to abstract the outbox.Manager in a manner that allows
-// for mocking/testing
-type outboxManager interface {
+// OutboxManager is the source of outbox messages routed by an OutboxRouter, it abstracts the outbox manager in a
+// manner that allows for mocking/testing
+type OutboxManager interface {
 	Advance(id int64)
 	Messages() chan *Message
 }
@@ -20,15 +20,14 @@ type outboxManager interface {
 // OutboxRouter is the interface for a service that routes outbox messages to a handler
 type OutboxRouter interface {
 	HandlerFunc(event string, f OutboxHandlerFunc)
-	WithMiddleware(f MiddlewareFunc) OutboxRouter``
+	WithMiddleware(f MiddlewareFunc) OutboxRouter
 	ListenAndServeAsync()
 	ListenAndServe()
 	Shutdown()
 }
 
-// NewOutboxRouter constructs a new outbox router off the given table and lock
-func NewOutboxRouter(dbWriter *sqlx.DB, table string, lock string) OutboxRouter {
-	manager := outbox.NewManager(dbWriter, table, lock)
+// NewOutboxRouter constructs a new outbox router off the given outbox manager
+func NewOutboxRouter(manager OutboxManager) OutboxRouter {
 	return &outboxRouter{manager: manager, handlers: make(map[string]OutboxHandlerFunc, 0)}
 }
 
@@ -36,13 +35,14 @@ func NewOutboxRouter(dbWriter *sqlx.DB, table string, lock string) OutboxRouter
 // a switch statement for processing of msgs this works via registration of a handler for a
 // regex pattern for the event.
 type outboxRouter struct {
-	manager    outboxManager
+	manager    OutboxManager
 	handlers   map[string]OutboxHandlerFunc
 	middleware []MiddlewareFunc
 }
 
 func (o *outboxRouter) WithMiddleware(f MiddlewareFunc) OutboxRouter {
 	o.middleware = append(o.middleware, f)
+	return o
 }
 
 // HandlerFunc adds a new handler for a given event - event can be a regex
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// Backend is a destination for metrics, instruments are created once up front with the names of their labels
+// and then given the label values each time they are recorded
+type Backend interface {
+	Timer(name string, description string, labelNames []string) (Timer, error)
+	Counter(name string, description string, labelNames []string) (Counter, error)
+	Gauge(name string, description string, labelNames []string) (Gauge, error)
+}
+
+// Timer records how long something took
+type Timer interface {
+	Record(ctx context.Context, d time.Duration, labelValues ...string)
+}
+
+// Counter records a value that only ever goes up
+type Counter interface {
+	Add(ctx context.Context, delta float64, labelValues ...string)
+}
+
+// Gauge records a value that can go up and down
+type Gauge interface {
+	Add(ctx context.Context, delta float64, labelValues ...string)
+}
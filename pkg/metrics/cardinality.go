@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OverflowLabelValue replaces the unbounded label values of a combination beyond the cardinality limit
+	OverflowLabelValue = "other"
+	// DroppedLabelsMetric counts the recordings whose labels were replaced by OverflowLabelValue
+	DroppedLabelsMetric = "metric_labels_dropped_total"
+
+	droppedLabelsMetricLabel = "metric"
+	labelValueSeparator      = "\xff"
+)
+
+// LimitCardinality wraps the backend so that each instrument it creates records at most limit distinct label
+// combinations, any others are recorded with every label other than the bounded labels set to OverflowLabelValue
+// and counted in DroppedLabelsMetric. Bounded labels (e.g. a result) have few values so are kept to leave those
+// series distinguishable. A limit of zero or less is unlimited
+func LimitCardinality(backend Backend, limit int, boundedLabels ...string) Backend {
+	if limit <= 0 {
+		return backend
+	}
+	return &limitedBackend{backend: backend, limit: limit, boundedLabels: boundedLabels}
+}
+
+type limitedBackend struct {
+	backend       Backend
+	limit         int
+	boundedLabels []string
+}
+
+func (l *limitedBackend) Timer(name string, description string, labelNames []string) (Timer, error) {
+	timer, err := l.backend.Timer(name, description, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := l.limiter(name, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return LimitTimer(timer, limiter), nil
+}
+
+func (l *limitedBackend) Counter(name string, description string, labelNames []string) (Counter, error) {
+	counter, err := l.backend.Counter(name, description, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := l.limiter(name, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return LimitCounter(counter, limiter), nil
+}
+
+func (l *limitedBackend) Gauge(name string, description string, labelNames []string) (Gauge, error) {
+	gauge, err := l.backend.Gauge(name, description, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := l.limiter(name, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return LimitGauge(gauge, limiter), nil
+}
+
+func (l *limitedBackend) limiter(name string, labelNames []string) (*CardinalityLimiter, error) {
+	dropped, err := DroppedLabelsCounter(l.backend)
+	if err != nil {
+		return nil, err
+	}
+	return NewCardinalityLimiter(name, l.limit, dropped, LabelPositions(labelNames, l.boundedLabels...)...), nil
+}
+
+// LabelPositions gets the positions of the named labels in the label names, names that aren't found are ignored
+func LabelPositions(labelNames []string, names ...string) []int {
+	positions := make([]int, 0, len(names))
+	for i, labelName := range labelNames {
+		for _, name := range names {
+			if labelName == name {
+				positions = append(positions, i)
+				break
+			}
+		}
+	}
+	return positions
+}
+
+// DroppedLabelsCounter gets the counter of DroppedLabelsMetric from the backend
+func DroppedLabelsCounter(backend Backend) (Counter, error) {
+	return backend.Counter(DroppedLabelsMetric,
+		"Number of recordings whose labels were replaced because the metric reached its cardinality limit",
+		[]string{droppedLabelsMetricLabel})
+}
+
+// NewCardinalityLimiter creates a limiter admitting the first limit distinct label combinations of the named
+// metric, the dropped counter (if not nil) is incremented each time a combination is replaced. The values of the
+// labels at the bounded positions are kept when a combination is replaced
+func NewCardinalityLimiter(metric string, limit int, dropped Counter, bounded ...int) *CardinalityLimiter {
+	return &CardinalityLimiter{metric: metric, limit: limit, dropped: dropped, bounded: bounded,
+		seen: make(map[string]struct{})}
+}
+
+// CardinalityLimiter caps the distinct label combinations recorded by an instrument, once a combination has been
+// admitted it is always admitted so gauges stay balanced
+type CardinalityLimiter struct {
+	metric  string
+	limit   int
+	dropped Counter
+	bounded []int
+
+	mux  sync.RWMutex
+	seen map[string]struct{}
+}
+
+// Limit gets the label values to record, either those given or OverflowLabelValue for each label that isn't
+// bounded
+func (c *CardinalityLimiter) Limit(ctx context.Context, labelValues []string) []string {
+	key := strings.Join(labelValues, labelValueSeparator)
+
+	c.mux.RLock()
+	_, found := c.seen[key]
+	full := len(c.seen) >= c.limit
+	c.mux.RUnlock()
+	if found {
+		return labelValues
+	}
+
+	if !full {
+		c.mux.Lock()
+		_, found = c.seen[key]
+		if !found && len(c.seen) < c.limit {
+			c.seen[key] = struct{}{}
+			found = true
+		}
+		c.mux.Unlock()
+		if found {
+			return labelValues
+		}
+	}
+
+	if c.dropped != nil {
+		c.dropped.Add(ctx, 1, c.metric)
+	}
+	overflow := make([]string, len(labelValues))
+	for i := range overflow {
+		overflow[i] = OverflowLabelValue
+	}
+	for _, i := range c.bounded {
+		if i < len(labelValues) {
+			overflow[i] = labelValues[i]
+		}
+	}
+	return overflow
+}
+
+// LimitTimer wraps the timer so its label values are limited by the limiter
+func LimitTimer(timer Timer, limiter *CardinalityLimiter) Timer {
+	return &limitedTimer{timer: timer, limiter: limiter}
+}
+
+type limitedTimer struct {
+	timer   Timer
+	limiter *CardinalityLimiter
+}
+
+func (l *limitedTimer) Record(ctx context.Context, d time.Duration, labelValues ...string) {
+	l.timer.Record(ctx, d, l.limiter.Limit(ctx, labelValues)...)
+}
+
+// LimitCounter wraps the counter so its label values are limited by the limiter
+func LimitCounter(counter Counter, limiter *CardinalityLimiter) Counter {
+	return &limitedCounter{counter: counter, limiter: limiter}
+}
+
+type limitedCounter struct {
+	counter Counter
+	limiter *CardinalityLimiter
+}
+
+func (l *limitedCounter) Add(ctx context.Context, delta float64, labelValues ...string) {
+	l.counter.Add(ctx, delta, l.limiter.Limit(ctx, labelValues)...)
+}
+
+// LimitGauge wraps the gauge so its label values are limited by the limiter
+func LimitGauge(gauge Gauge, limiter *CardinalityLimiter) Gauge {
+	return &limitedGauge{gauge: gauge, limiter: limiter}
+}
+
+type limitedGauge struct {
+	gauge   Gauge
+	limiter *CardinalityLimiter
+}
+
+func (l *limitedGauge) Add(ctx context.Context, delta float64, labelValues ...string) {
+	l.gauge.Add(ctx, delta, l.limiter.Limit(ctx, labelValues)...)
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinalityLimiter(t *testing.T) {
+	t.Run("collapse_overflow", func(t *testing.T) {
+		// given
+		limiter := NewCardinalityLimiter("test", 2, nil)
+		ctx := context.Background()
+
+		// when
+		first := limiter.Limit(ctx, []string{"Method1", "success"})
+		second := limiter.Limit(ctx, []string{"Method2", "success"})
+		overflow := limiter.Limit(ctx, []string{"Method3", "success"})
+		repeat := limiter.Limit(ctx, []string{"Method1", "success"})
+
+		// then
+		assert.Equal(t, []string{"Method1", "success"}, first)
+		assert.Equal(t, []string{"Method2", "success"}, second)
+		assert.Equal(t, []string{OverflowLabelValue, OverflowLabelValue}, overflow)
+		assert.Equal(t, []string{"Method1", "success"}, repeat)
+	})
+
+	t.Run("keep_bounded", func(t *testing.T) {
+		// given
+		limiter := NewCardinalityLimiter("test", 1, nil, 1)
+		ctx := context.Background()
+
+		// when
+		_ = limiter.Limit(ctx, []string{"Method1", "success"})
+		success := limiter.Limit(ctx, []string{"Method2", "success"})
+		failure := limiter.Limit(ctx, []string{"Method3", "failure"})
+
+		// then
+		assert.Equal(t, []string{OverflowLabelValue, "success"}, success)
+		assert.Equal(t, []string{OverflowLabelValue, "failure"}, failure)
+	})
+
+	t.Run("limited_backend", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		backend := LimitCardinality(NewPrometheusBackend(registry), 1, "service")
+		labels := []string{"service", "method"}
+
+		timer, err := backend.Timer("test_latency", "for testing", labels)
+		require.Nil(t, err)
+		counter, err := backend.Counter("test_calls", "for testing", labels)
+		require.Nil(t, err)
+		gauge, err := backend.Gauge("test_in_flight", "for testing", labels)
+		require.Nil(t, err)
+
+		// when
+		for _, method := range []string{"Method1", "Method2", "Method3"} {
+			timer.Record(context.Background(), time.Millisecond, "svc", method)
+			counter.Add(context.Background(), 1, "svc", method)
+			gauge.Add(context.Background(), 1, "svc", method)
+		}
+
+		// then
+		calls := counter.(*limitedCounter).counter.(*prometheusCounter).counter
+		assert.Equal(t, float64(1), testutil.ToFloat64(calls.WithLabelValues("svc", "Method1")))
+		assert.Equal(t, float64(2), testutil.ToFloat64(calls.WithLabelValues("svc", OverflowLabelValue)))
+		inFlight := gauge.(*limitedGauge).gauge.(*prometheusGauge).gauge
+		assert.Equal(t, float64(2), testutil.ToFloat64(inFlight.WithLabelValues("svc", OverflowLabelValue)))
+
+		dropped, err := DroppedLabelsCounter(NewPrometheusBackend(registry))
+		require.Nil(t, err)
+		droppedVec := dropped.(*prometheusCounter).counter
+		assert.Equal(t, float64(2), testutil.ToFloat64(droppedVec.WithLabelValues("test_latency")))
+		assert.Equal(t, float64(2), testutil.ToFloat64(droppedVec.WithLabelValues("test_calls")))
+		assert.Equal(t, float64(2), testutil.ToFloat64(droppedVec.WithLabelValues("test_in_flight")))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		// given
+		backend := NewPrometheusBackend(prometheus.NewRegistry())
+
+		// when
+		limited := LimitCardinality(backend, 0)
+
+		// then
+		assert.Equal(t, backend, limited)
+	})
+}
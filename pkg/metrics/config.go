@@ -3,4 +3,16 @@ package metrics
 type MetricsConfig struct {
 	Port int
 	URL  string
+
+	// LivenessURL and ReadinessURL are the paths of the health probes, defaulting to /healthz and /readyz
+	LivenessURL  string
+	ReadinessURL string
+
+	// TLSCertFile and TLSKeyFile serve over HTTPS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Username and Password protect the metrics (but not the probes) with basic auth when set
+	Username string
+	Password string
 }
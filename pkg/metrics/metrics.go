@@ -1,17 +1,34 @@
 package metrics
 
 import (
-	"fmt"
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func InitMetrics(cfg MetricsConfig) {
-	go ExposeMetrics(cfg)
+// InitMetrics starts a Server exposing the metrics in the background, the caller should Shutdown the returned
+// server on exit
+func InitMetrics(cfg MetricsConfig) (*Server, error) {
+	server := NewServer(cfg)
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+	return server, nil
 }
 
-func ExposeMetrics(cfg MetricsConfig) {
-	http.Handle(cfg.URL, promhttp.Handler())
-	_ = http.ListenAndServe(fmt.Sprintf(":%d", cfg.Port), nil)
+// ExposeMetrics serves the metrics until the server fails
+func ExposeMetrics(cfg MetricsConfig) error {
+	server := NewServer(cfg)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	return <-server.serveErr
+}
+
+// Handler gets the handler serving the metrics from the default registry, the OpenMetrics format is served to
+// scrapers that ask for it so exemplars (linking metrics to traces) are included
+func Handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
 }
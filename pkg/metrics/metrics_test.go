@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	t.Run("open_metrics", func(t *testing.T) {
+		// given
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+		resp := httptest.NewRecorder()
+
+		// when
+		Handler().ServeHTTP(resp, req)
+
+		// then
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Header().Get("Content-Type"), "application/openmetrics-text")
+	})
+
+	t.Run("text_format", func(t *testing.T) {
+		// given
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		resp := httptest.NewRecorder()
+
+		// when
+		Handler().ServeHTTP(resp, req)
+
+		// then
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Header().Get("Content-Type"), "text/plain")
+	})
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// OTelExporterOTLP exports metrics over OTLP/HTTP
+	OTelExporterOTLP = "otlp"
+	// OTelExporterStdout writes metrics as JSON, intended for local development
+	OTelExporterStdout = "stdout"
+
+	otelInstrumentationName = "github.com/jfbramlett/go-aop"
+)
+
+// OTelConfig configures the OpenTelemetry meter provider
+type OTelConfig struct {
+	// ServiceName is set as the service.name resource attribute
+	ServiceName string
+	// Exporter is either OTelExporterOTLP or OTelExporterStdout
+	Exporter string
+	// Endpoint is the host:port of the OTLP collector, the exporter default is used if empty
+	Endpoint string
+	// Insecure sends OTLP over plain HTTP
+	Insecure bool
+	// Interval is how often metrics are exported, the SDK default is used if zero
+	Interval time.Duration
+	// Writer is where the stdout exporter writes, os.Stdout if nil
+	Writer io.Writer
+}
+
+// NewOTelMeterProvider creates a meter provider periodically exporting to the configured exporter, the caller
+// should Shutdown the provider on exit to flush any remaining metrics
+func NewOTelMeterProvider(ctx context.Context, cfg OTelConfig) (*sdkmetric.MeterProvider, error) {
+	exporter, err := newOTelExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	var readerOpts []sdkmetric.PeriodicReaderOption
+	if cfg.Interval > 0 {
+		readerOpts = append(readerOpts, sdkmetric.WithInterval(cfg.Interval))
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, readerOpts...)),
+	), nil
+}
+
+func newOTelExporter(ctx context.Context, cfg OTelConfig) (sdkmetric.Exporter, error) {
+	switch cfg.Exporter {
+	case OTelExporterOTLP:
+		var opts []otlpmetrichttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	case OTelExporterStdout:
+		var opts []stdoutmetric.Option
+		if cfg.Writer != nil {
+			opts = append(opts, stdoutmetric.WithWriter(cfg.Writer))
+		}
+		return stdoutmetric.New(opts...)
+	default:
+		return nil, fmt.Errorf("unknown OpenTelemetry exporter %q", cfg.Exporter)
+	}
+}
+
+// NewOTelBackend creates a Backend recording through the given OpenTelemetry meter provider, label names become
+// attribute keys. Timers are histograms recording milliseconds, gauges are up/down counters
+func NewOTelBackend(provider metric.MeterProvider) Backend {
+	return &otelBackend{meter: provider.Meter(otelInstrumentationName)}
+}
+
+type otelBackend struct {
+	meter metric.Meter
+}
+
+func (o *otelBackend) Timer(name string, description string, labelNames []string) (Timer, error) {
+	histogram, err := o.meter.Float64Histogram(name, metric.WithDescription(description), metric.WithUnit("ms"))
+	if err != nil {
+		return nil, err
+	}
+	return &otelTimer{histogram: histogram, labelNames: labelNames}, nil
+}
+
+func (o *otelBackend) Counter(name string, description string, labelNames []string) (Counter, error) {
+	counter, err := o.meter.Float64Counter(name, metric.WithDescription(description))
+	if err != nil {
+		return nil, err
+	}
+	return &otelCounter{counter: counter, labelNames: labelNames}, nil
+}
+
+func (o *otelBackend) Gauge(name string, description string, labelNames []string) (Gauge, error) {
+	counter, err := o.meter.Float64UpDownCounter(name, metric.WithDescription(description))
+	if err != nil {
+		return nil, err
+	}
+	return &otelGauge{counter: counter, labelNames: labelNames}, nil
+}
+
+type otelTimer struct {
+	histogram  metric.Float64Histogram
+	labelNames []string
+}
+
+func (o *otelTimer) Record(ctx context.Context, d time.Duration, labelValues ...string) {
+	ms := float64(d.Nanoseconds()) / 1e6
+	o.histogram.Record(ctx, ms, otelAttributes(o.labelNames, labelValues))
+}
+
+type otelCounter struct {
+	counter    metric.Float64Counter
+	labelNames []string
+}
+
+func (o *otelCounter) Add(ctx context.Context, delta float64, labelValues ...string) {
+	o.counter.Add(ctx, delta, otelAttributes(o.labelNames, labelValues))
+}
+
+type otelGauge struct {
+	counter    metric.Float64UpDownCounter
+	labelNames []string
+}
+
+func (o *otelGauge) Add(ctx context.Context, delta float64, labelValues ...string) {
+	o.counter.Add(ctx, delta, otelAttributes(o.labelNames, labelValues))
+}
+
+func otelAttributes(labelNames []string, labelValues []string) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(labelNames))
+	for i, name := range labelNames {
+		if i >= len(labelValues) {
+			break
+		}
+		attrs = append(attrs, attribute.String(name, labelValues[i]))
+	}
+	return metric.WithAttributes(attrs...)
+}
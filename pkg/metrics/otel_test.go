@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOTelBackend(t *testing.T) {
+	t.Run("instruments", func(t *testing.T) {
+		// given
+		reader := sdkmetric.NewManualReader()
+		backend := NewOTelBackend(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+		timer, err := backend.Timer("test_latency", "for testing", []string{"method", "result"})
+		require.Nil(t, err)
+		counter, err := backend.Counter("test_calls", "for testing", []string{"method"})
+		require.Nil(t, err)
+		gauge, err := backend.Gauge("test_in_flight", "for testing", []string{"method"})
+		require.Nil(t, err)
+
+		// when
+		timer.Record(context.Background(), 2*time.Millisecond, "Method1", "success")
+		counter.Add(context.Background(), 2, "Method1")
+		gauge.Add(context.Background(), 1, "Method1")
+		gauge.Add(context.Background(), -1, "Method1")
+
+		// then
+		metrics := collectOTel(t, reader)
+
+		histogram := metrics["test_latency"].Data.(metricdata.Histogram[float64])
+		require.Len(t, histogram.DataPoints, 1)
+		assert.Equal(t, "ms", metrics["test_latency"].Unit)
+		assert.Equal(t, uint64(1), histogram.DataPoints[0].Count)
+		assert.Equal(t, float64(2), histogram.DataPoints[0].Sum)
+		assert.Equal(t, attribute.NewSet(attribute.String("method", "Method1"), attribute.String("result", "success")),
+			histogram.DataPoints[0].Attributes)
+
+		calls := metrics["test_calls"].Data.(metricdata.Sum[float64])
+		require.Len(t, calls.DataPoints, 1)
+		assert.True(t, calls.IsMonotonic)
+		assert.Equal(t, float64(2), calls.DataPoints[0].Value)
+
+		inFlight := metrics["test_in_flight"].Data.(metricdata.Sum[float64])
+		require.Len(t, inFlight.DataPoints, 1)
+		assert.False(t, inFlight.IsMonotonic)
+		assert.Equal(t, float64(0), inFlight.DataPoints[0].Value)
+	})
+
+	t.Run("stdout_exporter", func(t *testing.T) {
+		// given
+		var out bytes.Buffer
+		provider, err := NewOTelMeterProvider(context.Background(),
+			OTelConfig{ServiceName: "test", Exporter: OTelExporterStdout, Writer: &out})
+		require.Nil(t, err)
+		counter, err := NewOTelBackend(provider).Counter("test_calls", "for testing", []string{"method"})
+		require.Nil(t, err)
+
+		// when
+		counter.Add(context.Background(), 1, "Method1")
+		require.Nil(t, provider.Shutdown(context.Background()))
+
+		// then
+		assert.Contains(t, out.String(), `"test_calls"`)
+		assert.Contains(t, out.String(), `"Method1"`)
+		assert.Contains(t, out.String(), `"test"`)
+	})
+
+	t.Run("unknown_exporter", func(t *testing.T) {
+		// when
+		_, err := NewOTelMeterProvider(context.Background(), OTelConfig{Exporter: "carrier-pigeon"})
+
+		// then
+		assert.NotNil(t, err)
+	})
+}
+
+func collectOTel(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Metrics {
+	var rm metricdata.ResourceMetrics
+	require.Nil(t, reader.Collect(context.Background(), &rm))
+
+	metrics := make(map[string]metricdata.Metrics)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	traceIDLabel  = "trace_id"
+	summarySuffix = "_quantiles"
+)
+
+// NewPrometheusBackend creates a Backend that registers its instruments with the given registerer (or the global
+// registry if nil). Timers are Summaries named <name>_quantiles recording milliseconds, the same series the timed
+// advice records to by default. An instrument that is already registered is reused
+func NewPrometheusBackend(registerer prometheus.Registerer) Backend {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	return &prometheusBackend{registerer: registerer}
+}
+
+type prometheusBackend struct {
+	registerer prometheus.Registerer
+}
+
+func (p *prometheusBackend) Timer(name string, description string, labelNames []string) (Timer, error) {
+	summary := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name:       name + summarySuffix,
+		Help:       description,
+		Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.005},
+	}, labelNames)
+
+	collector, err := Register(p.registerer, summary)
+	if err != nil {
+		return nil, err
+	}
+	observer, ok := collector.(prometheus.ObserverVec)
+	if !ok {
+		return nil, alreadyRegisteredAsError(name)
+	}
+	return NewPrometheusTimer(observer), nil
+}
+
+func (p *prometheusBackend) Counter(name string, description string, labelNames []string) (Counter, error) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: description}, labelNames)
+
+	collector, err := Register(p.registerer, counter)
+	if err != nil {
+		return nil, err
+	}
+	counter, ok := collector.(*prometheus.CounterVec)
+	if !ok {
+		return nil, alreadyRegisteredAsError(name)
+	}
+	return &prometheusCounter{counter: counter}, nil
+}
+
+func (p *prometheusBackend) Gauge(name string, description string, labelNames []string) (Gauge, error) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: description}, labelNames)
+
+	collector, err := Register(p.registerer, gauge)
+	if err != nil {
+		return nil, err
+	}
+	gauge, ok := collector.(*prometheus.GaugeVec)
+	if !ok {
+		return nil, alreadyRegisteredAsError(name)
+	}
+	return &prometheusGauge{gauge: gauge}, nil
+}
+
+// Register registers the collector returning the collector already registered in its place if there is one
+func Register(registerer prometheus.Registerer, collector prometheus.Collector) (prometheus.Collector, error) {
+	err := registerer.Register(collector)
+	if err == nil {
+		return collector, nil
+	}
+
+	if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return are.ExistingCollector, nil
+	}
+	return nil, err
+}
+
+func alreadyRegisteredAsError(name string) error {
+	return fmt.Errorf("metric %s is already registered as a different type", name)
+}
+
+// NewPrometheusTimer creates a Timer recording milliseconds to the given Summary or Histogram, for histograms the
+// trace id of the span in the context is attached as an exemplar
+func NewPrometheusTimer(observer prometheus.ObserverVec) Timer {
+	return &prometheusTimer{observer: observer}
+}
+
+type prometheusTimer struct {
+	observer prometheus.ObserverVec
+}
+
+func (p *prometheusTimer) Record(ctx context.Context, d time.Duration, labelValues ...string) {
+	ms := float64(d.Nanoseconds()) / 1e6
+
+	observer := p.observer.WithLabelValues(labelValues...)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+		if traceID, found := tracing.TraceIDFromContext(ctx); found {
+			exemplarObserver.ObserveWithExemplar(ms, prometheus.Labels{traceIDLabel: traceID})
+			return
+		}
+	}
+	observer.Observe(ms)
+}
+
+type prometheusCounter struct {
+	counter *prometheus.CounterVec
+}
+
+func (p *prometheusCounter) Add(_ context.Context, delta float64, labelValues ...string) {
+	p.counter.WithLabelValues(labelValues...).Add(delta)
+}
+
+type prometheusGauge struct {
+	gauge *prometheus.GaugeVec
+}
+
+func (p *prometheusGauge) Add(_ context.Context, delta float64, labelValues ...string) {
+	p.gauge.WithLabelValues(labelValues...).Add(delta)
+}
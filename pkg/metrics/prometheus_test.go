@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusBackend(t *testing.T) {
+	t.Run("instruments", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		backend := NewPrometheusBackend(registry)
+
+		timer, err := backend.Timer("test_latency", "for testing", []string{"method"})
+		require.Nil(t, err)
+		counter, err := backend.Counter("test_calls", "for testing", []string{"method"})
+		require.Nil(t, err)
+		gauge, err := backend.Gauge("test_in_flight", "for testing", []string{"method"})
+		require.Nil(t, err)
+
+		// when
+		timer.Record(context.Background(), 2*time.Millisecond, "Method1")
+		counter.Add(context.Background(), 2, "Method1")
+		gauge.Add(context.Background(), 1, "Method1")
+		gauge.Add(context.Background(), -1, "Method1")
+
+		// then
+		count, err := testutil.GatherAndCount(registry, "test_latency_quantiles")
+		require.Nil(t, err)
+		assert.Equal(t, 1, count)
+		assert.Equal(t, float64(2), testutil.ToFloat64(counter.(*prometheusCounter).counter))
+		assert.Equal(t, float64(0), testutil.ToFloat64(gauge.(*prometheusGauge).gauge))
+	})
+
+	t.Run("reuse_registered", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		backend := NewPrometheusBackend(registry)
+		first, err := backend.Counter("test_calls", "for testing", []string{"method"})
+		require.Nil(t, err)
+
+		// when
+		second, err := backend.Counter("test_calls", "for testing", []string{"method"})
+
+		// then
+		require.Nil(t, err)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("registered_as_other_type", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		backend := NewPrometheusBackend(registry)
+		_, err := backend.Counter("test_calls", "for testing", []string{"method"})
+		require.Nil(t, err)
+
+		// when
+		_, err = backend.Gauge("test_calls", "for testing", []string{"method"})
+
+		// then
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/sirupsen/logrus"
+)
+
+const serviceNameGroupingKey = "service_name"
+
+// PusherConfig configures pushing metrics to a Prometheus Pushgateway
+type PusherConfig struct {
+	// URL is the address of the Pushgateway
+	URL string
+	// Job is the job label of the pushed metrics, defaulting to the service name
+	Job string
+	// ServiceName is added to the grouping key as service_name
+	ServiceName string
+	// Grouping adds further labels to the grouping key
+	Grouping map[string]string
+	// Interval is how often metrics are pushed, if zero they are only pushed on Push and Shutdown
+	Interval time.Duration
+	// Gatherer is the source of the metrics, the default registry if nil
+	Gatherer prometheus.Gatherer
+	// Username and Password are sent as basic auth when set
+	Username string
+	Password string
+}
+
+// NewPusher creates a Pusher pushing metrics to the Pushgateway every Interval, for short lived jobs that exit
+// before they can be scraped. Shutdown pushes the metrics one last time
+func NewPusher(cfg PusherConfig) (*Pusher, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("a Pushgateway URL is required")
+	}
+	if cfg.Job == "" {
+		cfg.Job = cfg.ServiceName
+	}
+	if cfg.Job == "" {
+		return nil, errors.New("a job or service name is required")
+	}
+	if cfg.Gatherer == nil {
+		cfg.Gatherer = prometheus.DefaultGatherer
+	}
+
+	pusher := push.New(cfg.URL, cfg.Job).Gatherer(cfg.Gatherer)
+	if cfg.ServiceName != "" {
+		pusher = pusher.Grouping(serviceNameGroupingKey, cfg.ServiceName)
+	}
+	for name, value := range cfg.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	if cfg.Username != "" || cfg.Password != "" {
+		pusher = pusher.BasicAuth(cfg.Username, cfg.Password)
+	}
+
+	p := &Pusher{cfg: cfg, pusher: pusher, done: make(chan struct{})}
+	if cfg.Interval > 0 {
+		p.wg.Add(1)
+		go p.pushLoop()
+	}
+
+	return p, nil
+}
+
+// Pusher pushes metrics to a Prometheus Pushgateway
+type Pusher struct {
+	cfg    PusherConfig
+	pusher *push.Pusher
+
+	mux sync.Mutex
+
+	shutdownOnce sync.Once
+	done         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Push pushes the current metrics, replacing those previously pushed with the same grouping key
+func (p *Pusher) Push(ctx context.Context) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.pusher.PushContext(ctx)
+}
+
+// Shutdown stops pushing on the interval and pushes the metrics one last time
+func (p *Pusher) Shutdown(ctx context.Context) error {
+	var err error
+	p.shutdownOnce.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		err = p.Push(ctx)
+	})
+	return err
+}
+
+func (p *Pusher) pushLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Interval)
+			if err := p.Push(ctx); err != nil {
+				logger, _ := logging.LoggerFromContext(ctx)
+				logger.WithFields(logrus.Fields{"url": p.cfg.URL, "job": p.cfg.Job}).WithError(err).
+					Warn("failed to push metrics")
+			}
+			cancel()
+		case <-p.done:
+			return
+		}
+	}
+}
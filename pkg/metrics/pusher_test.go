@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPusher(t *testing.T) {
+	t.Run("push_on_shutdown", func(t *testing.T) {
+		// given
+		gateway := newPushgateway(t)
+		registry := prometheus.NewRegistry()
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_pushed_total", Help: "for testing"})
+		registry.MustRegister(counter)
+
+		pusher, err := NewPusher(PusherConfig{URL: gateway.URL, ServiceName: "batch", Gatherer: registry,
+			Grouping: map[string]string{"instance": "host1"}, Username: "push", Password: "secret"})
+		require.Nil(t, err)
+		counter.Inc()
+
+		// when
+		err = pusher.Shutdown(context.Background())
+
+		// then
+		require.Nil(t, err)
+		require.Nil(t, pusher.Shutdown(context.Background()))
+		pushes := gateway.Pushes()
+		require.Equal(t, 1, len(pushes))
+		assert.Equal(t, http.MethodPut, pushes[0].method)
+		assert.True(t, strings.HasPrefix(pushes[0].path, "/metrics/job/batch/"))
+		assert.Contains(t, pushes[0].path, "/service_name/batch")
+		assert.Contains(t, pushes[0].path, "/instance/host1")
+		assert.Equal(t, "push", pushes[0].username)
+		assert.Contains(t, pushes[0].body, "test_pushed_total")
+	})
+
+	t.Run("push_on_interval", func(t *testing.T) {
+		// given
+		gateway := newPushgateway(t)
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_pushed_total", Help: "for testing"}))
+
+		// when
+		pusher, err := NewPusher(PusherConfig{URL: gateway.URL, Job: "cli", ServiceName: "batch", Gatherer: registry,
+			Interval: 10 * time.Millisecond})
+		require.Nil(t, err)
+		defer pusher.Shutdown(context.Background())
+
+		// then
+		assert.Eventually(t, func() bool { return len(gateway.Pushes()) >= 2 }, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, "/metrics/job/cli/service_name/batch", gateway.Pushes()[0].path)
+	})
+
+	t.Run("push_error", func(t *testing.T) {
+		// given
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer gateway.Close()
+
+		pusher, err := NewPusher(PusherConfig{URL: gateway.URL, ServiceName: "batch", Gatherer: prometheus.NewRegistry()})
+		require.Nil(t, err)
+
+		// when
+		err = pusher.Push(context.Background())
+
+		// then
+		assert.NotNil(t, err)
+	})
+
+	t.Run("missing_config", func(t *testing.T) {
+		// when
+		_, noURL := NewPusher(PusherConfig{ServiceName: "batch"})
+		_, noJob := NewPusher(PusherConfig{URL: "http://localhost:9091"})
+
+		// then
+		assert.NotNil(t, noURL)
+		assert.NotNil(t, noJob)
+	})
+}
+
+type pushRequest struct {
+	method   string
+	path     string
+	username string
+	body     string
+}
+
+type pushgateway struct {
+	*httptest.Server
+
+	mux    sync.Mutex
+	pushes []pushRequest
+}
+
+func newPushgateway(t *testing.T) *pushgateway {
+	gateway := &pushgateway{}
+	gateway.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		username, _, _ := r.BasicAuth()
+
+		gateway.mux.Lock()
+		gateway.pushes = append(gateway.pushes, pushRequest{method: r.Method, path: r.URL.Path, username: username, body: string(body)})
+		gateway.mux.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(gateway.Close)
+	return gateway
+}
+
+func (p *pushgateway) Pushes() []pushRequest {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return append([]pushRequest{}, p.pushes...)
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	defaultMetricsURL   = "/metrics"
+	defaultLivenessURL  = "/healthz"
+	defaultReadinessURL = "/readyz"
+)
+
+// ReadinessCheck reports whether a dependency is ready to serve traffic
+type ReadinessCheck func(ctx context.Context) error
+
+// NewServer creates a server exposing the metrics along with liveness and readiness probes on its own mux, it
+// starts out ready (subject to any readiness checks)
+func NewServer(cfg MetricsConfig) *Server {
+	if cfg.URL == "" {
+		cfg.URL = defaultMetricsURL
+	}
+	if cfg.LivenessURL == "" {
+		cfg.LivenessURL = defaultLivenessURL
+	}
+	if cfg.ReadinessURL == "" {
+		cfg.ReadinessURL = defaultReadinessURL
+	}
+
+	s := &Server{cfg: cfg, serveErr: make(chan error, 1), checks: make(map[string]ReadinessCheck)}
+	s.ready.Store(true)
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.URL, s.basicAuth(Handler()))
+	mux.HandleFunc(cfg.LivenessURL, s.liveness)
+	mux.HandleFunc(cfg.ReadinessURL, s.readiness)
+	s.server = &http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: mux}
+
+	return s
+}
+
+// Server serves metrics and health probes
+type Server struct {
+	cfg      MetricsConfig
+	server   *http.Server
+	listener net.Listener
+	serveErr chan error
+
+	ready  atomic.Bool
+	mux    sync.RWMutex
+	checks map[string]ReadinessCheck
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// Start listens on the configured port and serves in the background, an error binding the port, loading the
+// TLS certificate or in a partial TLS or basic auth config is returned rather than lost
+func (s *Server) Start() error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		listener = tls.NewListener(listener, s.server.TLSConfig)
+	}
+
+	s.listener = listener
+	go func() {
+		s.serveErr <- s.server.Serve(listener)
+	}()
+	return nil
+}
+
+// validate checks the TLS and basic auth settings are either both set or both unset, so a partial config doesn't
+// silently serve plain HTTP or accept any password
+func (s *Server) validate() error {
+	if (s.cfg.TLSCertFile == "") != (s.cfg.TLSKeyFile == "") {
+		return errors.New("both a TLS certificate and key file are required to serve metrics over TLS")
+	}
+	if (s.cfg.Username == "") != (s.cfg.Password == "") {
+		return errors.New("both a username and password are required for basic auth on the metrics endpoint")
+	}
+	return nil
+}
+
+// Addr gets the address the server is listening on, nil until started
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Shutdown marks the server as not ready then waits for in flight requests to complete (or the context to be
+// done) before closing, an error that stopped the server early is returned
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.SetReady(false)
+	if s.listener == nil {
+		return nil
+	}
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	s.shutdownOnce.Do(func() {
+		if err := <-s.serveErr; !errors.Is(err, http.ErrServerClosed) {
+			s.shutdownErr = err
+		}
+	})
+	return s.shutdownErr
+}
+
+// SetReady changes whether the readiness probe reports the service as ready
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// AddReadinessCheck adds a check that must pass for the readiness probe to report the service as ready
+func (s *Server) AddReadinessCheck(name string, check ReadinessCheck) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.checks[name] = check
+}
+
+func (s *Server) liveness(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Server) readiness(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for name, check := range s.checks {
+		if err := check(r.Context()); err != nil {
+			http.Error(w, fmt.Sprintf("%s: %s", name, err), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Server) basicAuth(next http.Handler) http.Handler {
+	if s.cfg.Username == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(s.cfg.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.cfg.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
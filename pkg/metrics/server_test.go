@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient doesn't keep connections alive so a shut down server doesn't wait on a connection the client dialled
+// but never used
+var testClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+func TestServer(t *testing.T) {
+	t.Run("metrics_and_probes", func(t *testing.T) {
+		// given
+		server := startServer(t, MetricsConfig{})
+
+		// when
+		metricsResp := get(t, testClient, serverURL(server, "http", "/metrics"), "", "")
+		livenessResp := get(t, testClient, serverURL(server, "http", "/healthz"), "", "")
+		readinessResp := get(t, testClient, serverURL(server, "http", "/readyz"), "", "")
+
+		// then
+		assert.Equal(t, http.StatusOK, metricsResp)
+		assert.Equal(t, http.StatusOK, livenessResp)
+		assert.Equal(t, http.StatusOK, readinessResp)
+	})
+
+	t.Run("readiness_checks", func(t *testing.T) {
+		// given
+		server := startServer(t, MetricsConfig{ReadinessURL: "/ready"})
+		var dbErr error
+		server.AddReadinessCheck("db", func(ctx context.Context) error { return dbErr })
+		url := serverURL(server, "http", "/ready")
+
+		// when
+		ready := get(t, testClient, url, "", "")
+		dbErr = errors.New("connection refused")
+		checkFailed := get(t, testClient, url, "", "")
+		dbErr = nil
+		server.SetReady(false)
+		notReady := get(t, testClient, url, "", "")
+
+		// then
+		assert.Equal(t, http.StatusOK, ready)
+		assert.Equal(t, http.StatusServiceUnavailable, checkFailed)
+		assert.Equal(t, http.StatusServiceUnavailable, notReady)
+	})
+
+	t.Run("basic_auth", func(t *testing.T) {
+		// given
+		server := startServer(t, MetricsConfig{Username: "prometheus", Password: "secret"})
+		url := serverURL(server, "http", "")
+
+		// when
+		noAuth := get(t, testClient, url+"/metrics", "", "")
+		wrongAuth := get(t, testClient, url+"/metrics", "prometheus", "guess")
+		auth := get(t, testClient, url+"/metrics", "prometheus", "secret")
+		probe := get(t, testClient, url+"/healthz", "", "")
+
+		// then
+		assert.Equal(t, http.StatusUnauthorized, noAuth)
+		assert.Equal(t, http.StatusUnauthorized, wrongAuth)
+		assert.Equal(t, http.StatusOK, auth)
+		assert.Equal(t, http.StatusOK, probe)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		// given
+		certFile, keyFile, pool := writeCert(t)
+		server := startServer(t, MetricsConfig{TLSCertFile: certFile, TLSKeyFile: keyFile})
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}, DisableKeepAlives: true}}
+
+		// when
+		resp := get(t, client, serverURL(server, "https", "/metrics"), "", "")
+
+		// then
+		assert.Equal(t, http.StatusOK, resp)
+	})
+
+	t.Run("invalid_tls_cert", func(t *testing.T) {
+		// given
+		server := NewServer(MetricsConfig{TLSCertFile: "missing.pem", TLSKeyFile: "missing.key"})
+
+		// when
+		err := server.Start()
+
+		// then
+		assert.NotNil(t, err)
+	})
+
+	t.Run("partial_config", func(t *testing.T) {
+		for name, cfg := range map[string]MetricsConfig{
+			"cert_only":     {TLSCertFile: "server.pem"},
+			"key_only":      {TLSKeyFile: "server.key"},
+			"username_only": {Username: "prometheus"},
+			"password_only": {Password: "secret"},
+		} {
+			// given
+			server := NewServer(cfg)
+
+			// when
+			err := server.Start()
+
+			// then
+			assert.NotNil(t, err, name)
+			assert.Nil(t, server.Addr(), name)
+		}
+	})
+
+	t.Run("port_in_use", func(t *testing.T) {
+		// given
+		listener, err := net.Listen("tcp", ":0")
+		require.Nil(t, err)
+		defer listener.Close()
+
+		// when
+		_, err = InitMetrics(MetricsConfig{Port: listener.Addr().(*net.TCPAddr).Port})
+
+		// then
+		assert.NotNil(t, err)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		// given
+		server := NewServer(MetricsConfig{})
+		require.Nil(t, server.Start())
+		url := serverURL(server, "http", "/healthz")
+
+		// when
+		err := server.Shutdown(context.Background())
+
+		// then
+		require.Nil(t, err)
+		assert.Nil(t, server.Shutdown(context.Background()))
+		_, err = http.Get(url)
+		assert.NotNil(t, err)
+	})
+}
+
+func startServer(t *testing.T, cfg MetricsConfig) *Server {
+	server := NewServer(cfg)
+	require.Nil(t, server.Start())
+	t.Cleanup(func() {
+		assert.Nil(t, server.Shutdown(context.Background()))
+	})
+	return server
+}
+
+func serverURL(server *Server, scheme string, path string) string {
+	return fmt.Sprintf("%s://localhost:%d%s", scheme, server.Addr().(*net.TCPAddr).Port, path)
+}
+
+func get(t *testing.T, client *http.Client, url string, username string, password string) int {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.Nil(t, err)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func writeCert(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return certFile, keyFile, pool
+}
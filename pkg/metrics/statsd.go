@@ -0,0 +1,243 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStatsDFlushInterval = 100 * time.Millisecond
+	// defaultStatsDMaxPacketSize keeps packets within the MTU of a typical network
+	defaultStatsDMaxPacketSize = 1432
+)
+
+// StatsDConfig configures the StatsD backend
+type StatsDConfig struct {
+	// Address is the host:port of the StatsD agent
+	Address string
+	// Prefix is prepended to every metric name
+	Prefix string
+	// DogStatsD sends labels as DogStatsD tags, otherwise label values are appended to the metric name
+	DogStatsD bool
+	// Tags are sent with every metric (DogStatsD only)
+	Tags map[string]string
+	// SampleRate is the fraction of timings and counts to send, 0 sends everything
+	SampleRate float64
+	// FlushInterval is how often buffered metrics are sent
+	FlushInterval time.Duration
+	// MaxPacketSize is the largest UDP packet that will be sent
+	MaxPacketSize int
+}
+
+// NewStatsDBackend creates a Backend that sends metrics over UDP to a StatsD (or DogStatsD) agent, metrics are
+// buffered in to packets and sent every FlushInterval (or whenever a packet is full). Close flushes anything
+// still buffered
+func NewStatsDBackend(cfg StatsDConfig) (*StatsDBackend, error) {
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultStatsDFlushInterval
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = defaultStatsDMaxPacketSize
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+
+	s := &StatsDBackend{cfg: cfg, conn: conn, constTags: formatConstTags(cfg.Tags), done: make(chan struct{})}
+	s.wg.Add(1)
+	go s.flushLoop()
+
+	return s, nil
+}
+
+// StatsDBackend is a Backend sending metrics to a StatsD agent
+type StatsDBackend struct {
+	cfg       StatsDConfig
+	conn      net.Conn
+	constTags []string
+
+	mux    sync.Mutex
+	buffer bytes.Buffer
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func (s *StatsDBackend) Timer(name string, _ string, labelNames []string) (Timer, error) {
+	return &statsDInstrument{backend: s, name: name, labelNames: labelNames, metricType: "ms", sampled: true}, nil
+}
+
+func (s *StatsDBackend) Counter(name string, _ string, labelNames []string) (Counter, error) {
+	return &statsDInstrument{backend: s, name: name, labelNames: labelNames, metricType: "c", sampled: true}, nil
+}
+
+func (s *StatsDBackend) Gauge(name string, _ string, labelNames []string) (Gauge, error) {
+	return &statsDInstrument{backend: s, name: name, labelNames: labelNames, metricType: "g", signed: true}, nil
+}
+
+// Flush sends any buffered metrics
+func (s *StatsDBackend) Flush() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.flushLocked()
+}
+
+// Close flushes any buffered metrics and closes the connection to the agent
+func (s *StatsDBackend) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		err = s.Flush()
+		if closeErr := s.conn.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+func (s *StatsDBackend) flushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *StatsDBackend) flushLocked() error {
+	if s.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(s.buffer.Bytes())
+	s.buffer.Reset()
+	return err
+}
+
+// send buffers a single metric line, flushing first if the line won't fit in the current packet
+func (s *StatsDBackend) send(line string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.buffer.Len() > 0 && s.buffer.Len()+1+len(line) > s.cfg.MaxPacketSize {
+		_ = s.flushLocked()
+	}
+	if s.buffer.Len() > 0 {
+		s.buffer.WriteByte('\n')
+	}
+	s.buffer.WriteString(line)
+}
+
+type statsDInstrument struct {
+	backend    *StatsDBackend
+	name       string
+	labelNames []string
+	metricType string
+	// sampled instruments honor the sample rate, signed instruments send deltas with an explicit sign
+	sampled bool
+	signed  bool
+}
+
+func (s *statsDInstrument) Record(_ context.Context, d time.Duration, labelValues ...string) {
+	s.write(float64(d.Nanoseconds())/1e6, labelValues)
+}
+
+func (s *statsDInstrument) Add(_ context.Context, delta float64, labelValues ...string) {
+	s.write(delta, labelValues)
+}
+
+func (s *statsDInstrument) write(value float64, labelValues []string) {
+	cfg := s.backend.cfg
+	if s.sampled && cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate {
+		return
+	}
+
+	line := strings.Builder{}
+	line.WriteString(cfg.Prefix)
+	line.WriteString(s.name)
+	if !cfg.DogStatsD {
+		for _, v := range labelValues {
+			line.WriteByte('.')
+			line.WriteString(sanitizeStatsD(v))
+		}
+	}
+
+	line.WriteByte(':')
+	if s.signed && value >= 0 {
+		line.WriteByte('+')
+	}
+	line.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	line.WriteByte('|')
+	line.WriteString(s.metricType)
+
+	if s.sampled && cfg.SampleRate < 1 {
+		line.WriteString("|@")
+		line.WriteString(strconv.FormatFloat(cfg.SampleRate, 'f', -1, 64))
+	}
+
+	if cfg.DogStatsD {
+		tags := append([]string{}, s.backend.constTags...)
+		for i, v := range labelValues {
+			if i < len(s.labelNames) {
+				tags = append(tags, sanitizeStatsDTag(s.labelNames[i])+":"+sanitizeStatsDTag(v))
+			}
+		}
+		if len(tags) > 0 {
+			line.WriteString("|#")
+			line.WriteString(strings.Join(tags, ","))
+		}
+	}
+
+	s.backend.send(line.String())
+}
+
+func formatConstTags(tags map[string]string) []string {
+	formatted := make([]string, 0, len(tags))
+	for k, v := range tags {
+		formatted = append(formatted, sanitizeStatsDTag(k)+":"+sanitizeStatsDTag(v))
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+// sanitizeStatsD replaces anything that would break a metric name
+func sanitizeStatsD(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// sanitizeStatsDTag replaces the characters DogStatsD uses as separators
+func sanitizeStatsDTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', ':', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsDBackend(t *testing.T) {
+	t.Run("plain_statsd", func(t *testing.T) {
+		// given
+		listener := newUDPListener(t)
+		backend, err := NewStatsDBackend(StatsDConfig{Address: listener.LocalAddr().String(), Prefix: "svc."})
+		require.Nil(t, err)
+		defer backend.Close()
+
+		timer, _ := backend.Timer("latency", "", []string{"method", "result"})
+		counter, _ := backend.Counter("calls", "", []string{"method"})
+		gauge, _ := backend.Gauge("in_flight", "", []string{"method"})
+
+		// when
+		timer.Record(context.Background(), 1500*time.Microsecond, "(*my).Method", "success")
+		counter.Add(context.Background(), 1, "Method")
+		gauge.Add(context.Background(), -1, "Method")
+		require.Nil(t, backend.Flush())
+
+		// then
+		assert.Equal(t, []string{"svc.latency.__my__Method.success:1.5|ms", "svc.calls.Method:1|c", "svc.in_flight.Method:-1|g"},
+			readPacket(t, listener))
+	})
+
+	t.Run("dogstatsd_tags", func(t *testing.T) {
+		// given
+		listener := newUDPListener(t)
+		backend, err := NewStatsDBackend(StatsDConfig{Address: listener.LocalAddr().String(), DogStatsD: true,
+			Tags: map[string]string{"env": "test"}})
+		require.Nil(t, err)
+		defer backend.Close()
+
+		gauge, _ := backend.Gauge("in_flight", "", []string{"method"})
+
+		// when
+		gauge.Add(context.Background(), 1, "Method1")
+		require.Nil(t, backend.Flush())
+
+		// then
+		assert.Equal(t, []string{"in_flight:+1|g|#env:test,method:Method1"}, readPacket(t, listener))
+	})
+
+	t.Run("sample_rate", func(t *testing.T) {
+		// given
+		listener := newUDPListener(t)
+		backend, err := NewStatsDBackend(StatsDConfig{Address: listener.LocalAddr().String(), SampleRate: 0.5})
+		require.Nil(t, err)
+		defer backend.Close()
+
+		counter, _ := backend.Counter("calls", "", nil)
+
+		// when
+		for i := 0; i < 100; i++ {
+			counter.Add(context.Background(), 1)
+		}
+		require.Nil(t, backend.Flush())
+
+		// then
+		lines := readPacket(t, listener)
+		assert.True(t, len(lines) > 0 && len(lines) < 100)
+		assert.Equal(t, "calls:1|c|@0.5", lines[0])
+	})
+
+	t.Run("packets_split_at_max_size", func(t *testing.T) {
+		// given
+		listener := newUDPListener(t)
+		backend, err := NewStatsDBackend(StatsDConfig{Address: listener.LocalAddr().String(), MaxPacketSize: 20})
+		require.Nil(t, err)
+		defer backend.Close()
+
+		counter, _ := backend.Counter("calls", "", nil)
+
+		// when
+		counter.Add(context.Background(), 1)
+		counter.Add(context.Background(), 2)
+		counter.Add(context.Background(), 3)
+
+		// then
+		assert.Equal(t, []string{"calls:1|c", "calls:2|c"}, readPacket(t, listener))
+		require.Nil(t, backend.Close())
+		assert.Equal(t, []string{"calls:3|c"}, readPacket(t, listener))
+	})
+
+	t.Run("flushed_on_interval", func(t *testing.T) {
+		// given
+		listener := newUDPListener(t)
+		backend, err := NewStatsDBackend(StatsDConfig{Address: listener.LocalAddr().String(), FlushInterval: 10 * time.Millisecond})
+		require.Nil(t, err)
+		defer backend.Close()
+
+		counter, _ := backend.Counter("calls", "", nil)
+
+		// when
+		counter.Add(context.Background(), 1)
+
+		// then
+		assert.Equal(t, []string{"calls:1|c"}, readPacket(t, listener))
+	})
+}
+
+func newUDPListener(t *testing.T) net.PacketConn {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	return listener
+}
+
+func readPacket(t *testing.T, listener net.PacketConn) []string {
+	buf := make([]byte, 65536)
+	require.Nil(t, listener.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, _, err := listener.ReadFrom(buf)
+	require.Nil(t, err)
+	return strings.Split(string(buf[:n]), "\n")
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/jfbramlett/go-aop/pkg/logging"
 
 	"github.com/jfbramlett/go-aop/pkg/tracing"
-	"github.com/jfbramlett/go-aop/pkg/web"
 )
 
 type RequestProxy interface {
@@ -30,13 +29,14 @@ func NewTraceRequestProxy() RequestProxy {
 	return &TraceRequestProxy{BaseRequestProxy: BaseRequestProxy{}}
 }
 
+// TraceRequestProxy adds the trace headers of the configured propagators (see tracing.InjectHTTP) to outgoing requests,
+// the xxx-request-id header is only sent when the context carries a request id (it used to be sent empty otherwise)
 type TraceRequestProxy struct {
 	BaseRequestProxy
 }
 
 func (t *TraceRequestProxy) Before(ctx context.Context, r *http.Request) (*http.Request, error) {
-	requestId := tracing.GetTraceFromContext(ctx)
-	r.Header.Add(web.HeaderRequestId, requestId)
+	tracing.InjectHTTP(ctx, r.Header)
 	return r, nil
 }
 
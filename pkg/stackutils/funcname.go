@@ -0,0 +1,160 @@
+package stackutils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// FuncName is the structured form of a fully qualified function name as reported by the runtime
+// (e.g. "github.com/org/repo/pkg.(*MyStruct[...]).Method.func1")
+type FuncName struct {
+	// Full is the name that was parsed
+	Full string
+	// PackagePath is the import path of the package declaring the function
+	PackagePath string
+	// PackageName is the name the package is (most likely) imported as
+	PackageName string
+	// Receiver is the type of the method receiver (blank for plain functions)
+	Receiver string
+	// PointerReceiver flags if the receiver is a pointer
+	PointerReceiver bool
+	// Method is the name of the method or function
+	Method string
+	// Closures is the chain of anonymous functions nested inside Method (e.g. ["func1", "2"])
+	Closures []string
+	// TypeParams are the type parameters of a generic function or receiver, note the runtime elides these as "..."
+	TypeParams []string
+}
+
+// IsClosure checks if the name refers to an anonymous function rather than the method itself
+func (f FuncName) IsClosure() bool {
+	return len(f.Closures) > 0
+}
+
+// Qualifier gets the method name qualified by its receiver or, for a plain function, its package
+// (e.g. "MyStruct.Method" or "pkg.Function")
+func (f FuncName) Qualifier() string {
+	if f.Receiver != "" {
+		return f.Receiver + "." + f.Method
+	}
+	if f.PackageName != "" {
+		return f.PackageName + "." + f.Method
+	}
+	return f.Method
+}
+
+// ParseFuncName parses a fully qualified function name such as those returned by runtime.FuncForPC in to its parts
+// (unlike MethodNameFromFullPath and friends, which keep their original output as it ends up in metric labels)
+func ParseFuncName(fullName string) FuncName {
+	f := FuncName{Full: fullName}
+
+	// the package path ends at the first dot after the last slash, the runtime escapes any dots in the last
+	// element of the path (so gopkg.in/yaml.v2 is reported as gopkg.in/yaml%2ev2)
+	name := fullName
+	searchEnd := strings.IndexAny(name, "([")
+	if searchEnd < 0 {
+		searchEnd = len(name)
+	}
+	pkgStart := strings.LastIndex(name[:searchEnd], "/") + 1
+	if idx := strings.Index(name[pkgStart:], "."); idx >= 0 {
+		f.PackagePath = unescapePath(name[:pkgStart+idx])
+		f.PackageName = packageNameFromPath(f.PackagePath)
+		name = name[pkgStart+idx+1:]
+	}
+
+	segments := splitSegments(strings.TrimSuffix(name, "-fm"))
+	if len(segments) == 0 {
+		return f
+	}
+
+	switch {
+	case strings.HasPrefix(segments[0], "(") && len(segments) > 1:
+		receiver := strings.TrimSuffix(strings.TrimPrefix(segments[0], "("), ")")
+		f.PointerReceiver = strings.HasPrefix(receiver, "*")
+		f.Receiver, f.TypeParams = splitTypeParams(strings.TrimPrefix(receiver, "*"))
+		f.Method = segments[1]
+		segments = segments[2:]
+	case len(segments) > 1 && !isClosureSuffix(segments[1]):
+		// a value receiver isn't wrapped in parens (pkg.MyStruct.Method)
+		f.Receiver, f.TypeParams = splitTypeParams(segments[0])
+		f.Method = segments[1]
+		segments = segments[2:]
+	default:
+		f.Method, f.TypeParams = splitTypeParams(segments[0])
+		segments = segments[1:]
+	}
+
+	if len(segments) > 0 {
+		f.Closures = segments
+	}
+
+	return f
+}
+
+// splitSegments splits a name on dots ignoring any found within brackets or parens
+func splitSegments(name string) []string {
+	segments := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, c := range name {
+		switch c {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, name[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(name) {
+		segments = append(segments, name[start:])
+	}
+	return segments
+}
+
+// splitTypeParams splits a name like "List[int,string]" in to its name and type parameters
+func splitTypeParams(name string) (string, []string) {
+	idx := strings.Index(name, "[")
+	if idx < 0 || !strings.HasSuffix(name, "]") {
+		return name, nil
+	}
+
+	params := strings.Split(name[idx+1:len(name)-1], ",")
+	for i := range params {
+		params[i] = strings.TrimSpace(params[i])
+	}
+	return name[:idx], params
+}
+
+// unescapePath reverses the escaping the runtime applies to package paths (which may be applied twice for test
+// binaries)
+func unescapePath(path string) string {
+	for i := 0; i < 2 && strings.Contains(path, "%"); i++ {
+		unescaped, err := url.PathUnescape(path)
+		if err != nil {
+			break
+		}
+		path = unescaped
+	}
+	return path
+}
+
+// packageNameFromPath guesses the package name from its path, dropping major version suffixes such as /v2 or .v2
+func packageNameFromPath(path string) string {
+	elements := strings.Split(path, "/")
+	name := elements[len(elements)-1]
+	if isMajorVersion(name) && len(elements) > 1 {
+		name = elements[len(elements)-2]
+	}
+	if idx := strings.LastIndex(name, "."); idx > 0 && isMajorVersion(name[idx+1:]) {
+		name = name[:idx]
+	}
+	return name
+}
+
+func isMajorVersion(s string) bool {
+	return strings.HasPrefix(s, "v") && isDigits(s[1:])
+}
@@ -0,0 +1,100 @@
+package stackutils
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parseSample struct {
+}
+
+func (p *parseSample) PointerMethod() string {
+	return funcNameOf(1)
+}
+
+func (p parseSample) ValueMethod() string {
+	return funcNameOf(1)
+}
+
+func (p *parseSample) ClosureMethod() string {
+	return func() string {
+		return funcNameOf(1)
+	}()
+}
+
+func parseSampleFunc() string {
+	return funcNameOf(1)
+}
+
+func funcNameOf(skip int) string {
+	pc, _, _, _ := runtime.Caller(skip)
+	return runtime.FuncForPC(pc).Name()
+}
+
+func TestParseFuncName(t *testing.T) {
+	const pkgPath = "github.com/jfbramlett/go-aop/pkg/stackutils"
+
+	tests := []struct {
+		name     string
+		fullName string
+		expected FuncName
+	}{
+		{"function", parseSampleFunc(),
+			FuncName{PackagePath: pkgPath, PackageName: "stackutils", Method: "parseSampleFunc"}},
+		{"pointer_receiver", (&parseSample{}).PointerMethod(),
+			FuncName{PackagePath: pkgPath, PackageName: "stackutils", Receiver: "parseSample", PointerReceiver: true, Method: "PointerMethod"}},
+		{"value_receiver", parseSample{}.ValueMethod(),
+			FuncName{PackagePath: pkgPath, PackageName: "stackutils", Receiver: "parseSample", Method: "ValueMethod"}},
+		{"method_closure", (&parseSample{}).ClosureMethod(),
+			FuncName{PackagePath: pkgPath, PackageName: "stackutils", Receiver: "parseSample", PointerReceiver: true, Method: "ClosureMethod", Closures: []string{"func1"}}},
+		{"method_value", runtime.FuncForPC(reflect.ValueOf(parseSample{}.ValueMethod).Pointer()).Name(),
+			FuncName{PackagePath: pkgPath, PackageName: "stackutils", Receiver: "parseSample", Method: "ValueMethod"}},
+		// the remaining names were captured from runtime.FuncForPC output, nested closures were named func1.1 before go1.22
+		{"nested_closure", "example.com/gen/sub.TestX.func1.func1",
+			FuncName{PackagePath: "example.com/gen/sub", PackageName: "sub", Method: "TestX", Closures: []string{"func1", "func1"}}},
+		{"nested_closure_pre_go1_22", "example.com/gen/sub.TestX.func1.1",
+			FuncName{PackagePath: "example.com/gen/sub", PackageName: "sub", Method: "TestX", Closures: []string{"func1", "1"}}},
+		{"generic_pointer_receiver", "example.com/gen/sub.(*List[...]).Push",
+			FuncName{PackagePath: "example.com/gen/sub", PackageName: "sub", Receiver: "List", PointerReceiver: true, Method: "Push", TypeParams: []string{"..."}}},
+		{"generic_value_receiver", "example.com/gen/sub.List[...].Len",
+			FuncName{PackagePath: "example.com/gen/sub", PackageName: "sub", Receiver: "List", Method: "Len", TypeParams: []string{"..."}}},
+		{"generic_function", "example.com/gen/sub.Map[...]",
+			FuncName{PackagePath: "example.com/gen/sub", PackageName: "sub", Method: "Map", TypeParams: []string{"..."}}},
+		{"generic_closure", "example.com/gen/sub.(*List[...]).Closure.func1",
+			FuncName{PackagePath: "example.com/gen/sub", PackageName: "sub", Receiver: "List", PointerReceiver: true, Method: "Closure", Closures: []string{"func1"}, TypeParams: []string{"..."}}},
+		{"dotted_package", "gopkg.in/yaml%2ev2.(*Decoder).Decode",
+			FuncName{PackagePath: "gopkg.in/yaml.v2", PackageName: "yaml", Receiver: "Decoder", PointerReceiver: true, Method: "Decode"}},
+		{"dotted_package_test_binary", "example.com/gen.v2/sub%252ev3.(*List[...]).Closure.func1",
+			FuncName{PackagePath: "example.com/gen.v2/sub.v3", PackageName: "sub", Receiver: "List", PointerReceiver: true, Method: "Closure", Closures: []string{"func1"}, TypeParams: []string{"..."}}},
+		{"major_version_dir", "github.com/org/repo/v2.New",
+			FuncName{PackagePath: "github.com/org/repo/v2", PackageName: "repo", Method: "New"}},
+		{"main_package", "main.main",
+			FuncName{PackagePath: "main", PackageName: "main", Method: "main"}},
+		{"go_wrapper", "main.run.gowrap1",
+			FuncName{PackagePath: "main", PackageName: "main", Method: "run", Closures: []string{"gowrap1"}}},
+		{"no_package", "MyMethod",
+			FuncName{Method: "MyMethod"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			tc.expected.Full = tc.fullName
+
+			// when
+			funcName := ParseFuncName(tc.fullName)
+
+			// then
+			assert.Equal(t, tc.expected, funcName)
+		})
+	}
+}
+
+func TestFuncNameQualifier(t *testing.T) {
+	assert.Equal(t, "parseSample.PointerMethod", ParseFuncName((&parseSample{}).PointerMethod()).Qualifier())
+	assert.Equal(t, "stackutils.parseSampleFunc", ParseFuncName(parseSampleFunc()).Qualifier())
+	assert.Equal(t, "MyMethod", ParseFuncName("MyMethod").Qualifier())
+}
@@ -6,21 +6,91 @@ import (
 	"strings"
 )
 
+const unknownMethod = "unknown"
+
+// GetCallingMethodName gets the fully qualified name of the method that called the method invoking this
 func GetCallingMethodName() string {
-	return GetMethodNameAt(3)
+	return CallerName(2)
 }
 
+// GetMethodNameAt gets the fully qualified name of the method idx frames up the stack, 0 being GetMethodNameAt
+// itself (mirroring runtime.Caller)
 func GetMethodNameAt(idx int) string {
-	pc, _, _, ok := runtime.Caller(idx)
-	details := runtime.FuncForPC(pc)
-	if ok && details != nil {
-		return details.Name()
+	return CallerName(idx)
+}
+
+// CallerName gets the fully qualified name of the method skip frames above the caller of CallerName, 0 being
+// the caller itself. Unlike runtime.Caller this counts inlined methods as frames so the result is the same
+// whether or not the compiler inlined anything along the way
+func CallerName(skip int) string {
+	return FuncNameForPC(CallerPC(skip + 1))
+}
+
+// CallerPC gets the program counter skip frames above the caller of CallerPC (0 being the caller itself), the
+// result is stable for a given call site so can be used as a cheap key for it. Returns 0 if there is no such frame
+func CallerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if CallerPCs(skip+1, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+// CallerPCs fills pcs with the program counters starting skip frames above the caller of CallerPCs (0 being the
+// caller itself) returning the number filled in, use FuncNameForPC on each to get the method names
+func CallerPCs(skip int, pcs []uintptr) int {
+	return runtime.Callers(skip+2, pcs)
+}
+
+// FuncNameForPC gets the fully qualified name of the method for a program counter returned by CallerPC
+func FuncNameForPC(pc uintptr) string {
+	if pc == 0 {
+		return unknownMethod
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.Function == "" {
+		return unknownMethod
 	}
+	return frame.Function
+}
+
+// EnclosingMethodName strips any closure suffixes (func1, func1.2, gowrap1, ...) from a fully qualified method
+// name giving the name of the method the closure was declared in
+func EnclosingMethodName(fullMethod string) string {
+	for {
+		idx := strings.LastIndex(fullMethod, ".")
+		if idx <= 0 || !isClosureSuffix(fullMethod[idx+1:]) {
+			return fullMethod
+		}
+		fullMethod = fullMethod[:idx]
+	}
+}
+
+// isClosureSuffix checks if the given name segment is one the compiler generates for a closure
+func isClosureSuffix(segment string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if strings.HasPrefix(segment, prefix) && isDigits(segment[len(prefix):]) {
+			return true
+		}
+	}
+	return isDigits(segment)
+}
 
-	return "unknown"
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
-// MethodNameFromFullPath get the method name from a full path string
+// MethodNameFromFullPath get the method name from a full path string. This keeps its original output (a closure
+// gives its func1 suffix) as it is used for metric labels, use ParseFuncName for the enclosing method
 func MethodNameFromFullPath(fullMethod string) string {
 	idx := strings.LastIndex(fullMethod, ".")
 	if idx > 0 {
@@ -45,6 +115,8 @@ func StructNameFromMethod(methodName string) string {
 	return ""
 }
 
+// BasicQualifierFromMethod gets the method name qualified by its struct, keeping its original output as
+// MethodNameFromFullPath does
 func BasicQualifierFromMethod(fullMethod string) string {
 	structName := StructNameFromMethod(fullMethod)
 	methodName := MethodNameFromFullPath(fullMethod)
@@ -94,3 +94,65 @@ func TestStructNameFromMethodt(t *testing.T) {
 		assert.Equal(t, expectedStructName, structName)
 	})
 }
+
+func TestCallerName(t *testing.T) {
+	t.Run("direct_caller", func(t *testing.T) {
+		// given
+		expectedMethodName := "github.com/jfbramlett/go-aop/pkg/stackutils.TestCallerName.func1"
+
+		// when
+		methodName := CallerName(0)
+
+		// then
+		assert.Equal(t, expectedMethodName, methodName)
+	})
+
+	t.Run("skip_inlined_helper", func(t *testing.T) {
+		// given
+		expectedMethodName := "github.com/jfbramlett/go-aop/pkg/stackutils.TestCallerName.func2"
+
+		// when
+		methodName := inlinedHelper()
+
+		// then
+		assert.Equal(t, expectedMethodName, methodName)
+	})
+
+	t.Run("past_top_of_stack", func(t *testing.T) {
+		// when
+		methodName := CallerName(1000)
+
+		// then
+		assert.Equal(t, unknownMethod, methodName)
+	})
+}
+
+func inlinedHelper() string {
+	return CallerName(1)
+}
+
+func TestEnclosingMethodName(t *testing.T) {
+	tests := []struct {
+		name       string
+		methodName string
+		expected   string
+	}{
+		{"method", "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1", "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1"},
+		{"closure", "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1.func1", "github.com/jfbramlett/go-aop/pkg/aop.(*sampleStruct).Method1"},
+		{"nested_closure", "github.com/jfbramlett/go-aop/pkg/aop.Method1.func1.2", "github.com/jfbramlett/go-aop/pkg/aop.Method1"},
+		{"nested_func_closure", "github.com/jfbramlett/go-aop/pkg/aop.Method1.func1.func2", "github.com/jfbramlett/go-aop/pkg/aop.Method1"},
+		{"go_wrapper", "github.com/jfbramlett/go-aop/pkg/aop.Method1.gowrap1", "github.com/jfbramlett/go-aop/pkg/aop.Method1"},
+		{"function_named_func", "github.com/jfbramlett/go-aop/pkg/aop.funcs", "github.com/jfbramlett/go-aop/pkg/aop.funcs"},
+		{"no_package", "func1", "func1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			methodName := EnclosingMethodName(tc.methodName)
+
+			// then
+			assert.Equal(t, tc.expected, methodName)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+var baggageKeys atomic.Value
+
+func init() {
+	SetBaggageKeys()
+}
+
+// SetBaggageKeys sets the allow-list of baggage keys that are copied to the logger in the context and can be used
+// as metric labels, baggage is otherwise only carried along with the request
+func SetBaggageKeys(keys ...string) {
+	allowed := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		allowed[key] = struct{}{}
+	}
+	baggageKeys.Store(allowed)
+}
+
+// BaggageKeyAllowed checks if the baggage key is on the allow-list
+func BaggageKeyAllowed(key string) bool {
+	_, found := baggageKeys.Load().(map[string]struct{})[key]
+	return found
+}
+
+func setBaggageKeys(cfg TracingConfig) {
+	if cfg.BaggageKeys != nil {
+		SetBaggageKeys(cfg.BaggageKeys...)
+	}
+}
+
+// SetBaggage adds an item to the baggage in the context, baggage follows the request across HTTP calls (with the
+// W3C baggage header) and messages. If the key is on the allow-list it is also added to the logger in the context
+func SetBaggage(ctx context.Context, key string, value string) (context.Context, error) {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return ctx, err
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+	if BaggageKeyAllowed(key) {
+		_, ctx = logging.UpdateInContext(ctx, logrus.Fields{key: value})
+	}
+	return ctx, nil
+}
+
+// Baggage gets all of the baggage items in the context
+func Baggage(ctx context.Context) map[string]string {
+	members := baggage.FromContext(ctx).Members()
+	items := make(map[string]string, len(members))
+	for _, member := range members {
+		items[member.Key()] = member.Value()
+	}
+	return items
+}
+
+// AllowedBaggage gets the baggage items in the context whose keys are on the allow-list
+func AllowedBaggage(ctx context.Context) map[string]string {
+	items := Baggage(ctx)
+	for key := range items {
+		if !BaggageKeyAllowed(key) {
+			delete(items, key)
+		}
+	}
+	return items
+}
+
+// ContextWithBaggage adds the baggage items to the context, as SetBaggage does for a single item. Baggage is best
+// effort so invalid items are skipped, the returned context carries the valid items and the error lists the
+// skipped ones
+func ContextWithBaggage(ctx context.Context, items map[string]string) (context.Context, error) {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	// added in order so the same items always give the same baggage
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		var err error
+		if ctx, err = SetBaggage(ctx, key, items[key]); err != nil {
+			errs = append(errs, fmt.Errorf("skipped baggage item %q: %w", key, err))
+		}
+	}
+	return ctx, errors.Join(errs...)
+}
+
+// logBaggage adds the allow-listed baggage items to the logger in the context
+func logBaggage(ctx context.Context) context.Context {
+	items := AllowedBaggage(ctx)
+	if len(items) == 0 {
+		return ctx
+	}
+
+	fields := make(logrus.Fields, len(items))
+	for key, value := range items {
+		fields[key] = value
+	}
+	_, ctx = logging.UpdateInContext(ctx, fields)
+	return ctx
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jfbramlett/go-aop/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaggage(t *testing.T) {
+	t.Run("set_and_get", func(t *testing.T) {
+		// given
+		ctx, err := SetBaggage(context.Background(), "tenant", "acme")
+		require.Nil(t, err)
+
+		// when
+		ctx, err = SetBaggage(ctx, "flag", "new-checkout")
+
+		// then
+		require.Nil(t, err)
+		assert.Equal(t, map[string]string{"tenant": "acme", "flag": "new-checkout"}, Baggage(ctx))
+		assert.Empty(t, Baggage(context.Background()))
+	})
+
+	t.Run("invalid_key", func(t *testing.T) {
+		// when
+		ctx, err := SetBaggage(context.Background(), "", "acme")
+
+		// then
+		assert.NotNil(t, err)
+		assert.Empty(t, Baggage(ctx))
+	})
+
+	t.Run("skip_invalid_items", func(t *testing.T) {
+		// when
+		ctx, err := ContextWithBaggage(context.Background(),
+			map[string]string{"tenant": "acme", "": "no-key", "flag": "new-checkout"})
+
+		// then
+		assert.NotNil(t, err)
+		assert.Equal(t, map[string]string{"tenant": "acme", "flag": "new-checkout"}, Baggage(ctx))
+	})
+
+	t.Run("allowed_keys_logged", func(t *testing.T) {
+		// given
+		SetBaggageKeys("tenant")
+		defer SetBaggageKeys()
+
+		// when
+		ctx, err := SetBaggage(context.Background(), "tenant", "acme")
+		require.Nil(t, err)
+		ctx, err = SetBaggage(ctx, "secret", "hidden")
+		require.Nil(t, err)
+
+		// then
+		logger, _ := logging.LoggerFromContext(ctx)
+		assert.Equal(t, "acme", logger.Data["tenant"])
+		assert.NotContains(t, logger.Data, "secret")
+		assert.Equal(t, map[string]string{"tenant": "acme"}, AllowedBaggage(ctx))
+	})
+
+	t.Run("http_round_trip", func(t *testing.T) {
+		// given
+		SetBaggageKeys("tenant")
+		defer SetBaggageKeys()
+
+		ctx, err := SetBaggage(context.Background(), "tenant", "acme")
+		require.Nil(t, err)
+		header := http.Header{}
+
+		// when
+		InjectHTTP(ctx, header)
+		serverCtx := ExtractHTTP(context.Background(), header)
+
+		// then
+		assert.Equal(t, "tenant=acme", header.Get("baggage"))
+		assert.Equal(t, map[string]string{"tenant": "acme"}, Baggage(serverCtx))
+		logger, _ := logging.LoggerFromContext(serverCtx)
+		assert.Equal(t, "acme", logger.Data["tenant"])
+	})
+
+	t.Run("config_allow_list", func(t *testing.T) {
+		// given
+		defer SetBaggageKeys()
+
+		// when
+		require.Nil(t, NewTracer(TracingConfig{Service: "baggage", Reporter: ReporterNoop, BaggageKeys: []string{"tenant"}}))
+
+		// then
+		assert.True(t, BaggageKeyAllowed("tenant"))
+		assert.False(t, BaggageKeyAllowed("secret"))
+	})
+}
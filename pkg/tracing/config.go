@@ -1,7 +1,36 @@
 package tracing
 
+import "github.com/sirupsen/logrus"
+
 type TracingConfig struct {
 	Service      string
 	ReporterUrl  string
 	ReporterPort uint16
+
+	// MaxQueueSize is the number of spans waiting to be sent to Zipkin before further spans are dropped,
+	// DefaultMaxQueueSize if not set
+	MaxQueueSize int
+
+	// HostPort is the address of this service recorded on its spans
+	HostPort string
+
+	// Reporter is where spans are sent, one of ReporterZipkin (the default), ReporterLog, ReporterLogrus,
+	// ReporterMemory, ReporterNoop, ReporterOTLPHTTP or ReporterOTLPGRPC
+	Reporter string
+	// Logger is where ReporterLogrus writes spans, if not set spans are written to stdout as text so the span tree
+	// is readable in the console
+	Logger *logrus.Entry
+
+	// Sampler decides which traces are recorded, one of SamplerAlways (the default), SamplerNever,
+	// SamplerProbabilistic or SamplerRateLimited
+	Sampler string
+	// SampleRate is the fraction of traces recorded by the probabilistic sampler or the number of traces a second
+	// recorded by the rate limited sampler
+	SampleRate float64
+
+	// Propagators are the formats span context is carried in across HTTP calls, DefaultPropagators if empty
+	Propagators []string
+
+	// BaggageKeys is the allow-list of baggage keys copied to log fields and usable as metric labels
+	BaggageKeys []string
 }
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ReporterLogrus writes spans as log entries to the config's Logger (or stdout as text), intended for local
+	// development
+	ReporterLogrus = "logrus"
+
+	// maxBufferedSpans is the number of spans held back waiting for their root to finish before they are written
+	// as they are
+	maxBufferedSpans = 10000
+
+	spanIndent = "  "
+)
+
+// NewLogrusReporter creates a reporter writing spans to the logger, the spans of a request are held until the
+// request's root span (one with no parent or a server span) finishes and are then written in the order they
+// started, indented under their parents. Each entry carries the trace, span and parent ids along with the start
+// offset from the root and the duration in milliseconds
+func NewLogrusReporter(logger *logrus.Entry) reporter.Reporter {
+	return &logrusReporter{logger: logger, traces: make(map[model.TraceID][]model.SpanModel)}
+}
+
+type logrusReporter struct {
+	logger *logrus.Entry
+
+	mux      sync.Mutex
+	traces   map[model.TraceID][]model.SpanModel
+	buffered int
+}
+
+func (l *logrusReporter) Send(span model.SpanModel) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	spans := append(l.traces[span.TraceID], span)
+	if !isLocalRoot(span) {
+		l.traces[span.TraceID] = spans
+		l.buffered++
+		if l.buffered > maxBufferedSpans {
+			l.flush()
+		}
+		return
+	}
+
+	l.buffered -= len(spans) - 1
+	delete(l.traces, span.TraceID)
+	remaining := l.writeTree(span, spans)
+	if len(remaining) > 0 {
+		// other requests in the same trace (e.g. calls back into this service)
+		l.traces[span.TraceID] = remaining
+		l.buffered += len(remaining)
+	}
+}
+
+// Close writes any spans still waiting for their root
+func (l *logrusReporter) Close() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.flush()
+	return nil
+}
+
+// flush writes all of the buffered spans without indentation
+func (l *logrusReporter) flush() {
+	for traceID, spans := range l.traces {
+		sortByStart(spans)
+		for _, span := range spans {
+			l.write(span, span.Timestamp, 0)
+		}
+		delete(l.traces, traceID)
+	}
+	l.buffered = 0
+}
+
+// writeTree writes the root and its descendants from the spans of its trace, returning the spans that aren't part
+// of its tree
+func (l *logrusReporter) writeTree(root model.SpanModel, spans []model.SpanModel) []model.SpanModel {
+	children := make(map[model.ID][]model.SpanModel)
+	for _, span := range spans {
+		if span.ParentID != nil && span.ID != root.ID {
+			children[*span.ParentID] = append(children[*span.ParentID], span)
+		}
+	}
+
+	var walk func(span model.SpanModel, depth int)
+	walk = func(span model.SpanModel, depth int) {
+		l.write(span, root.Timestamp, depth)
+		next := children[span.ID]
+		delete(children, span.ID)
+		sortByStart(next)
+		for _, child := range next {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+
+	var remaining []model.SpanModel
+	for _, orphans := range children {
+		remaining = append(remaining, orphans...)
+	}
+	return remaining
+}
+
+func (l *logrusReporter) write(span model.SpanModel, rootStart time.Time, depth int) {
+	fields := logrus.Fields{
+		TraceIDKey:    span.TraceID.String(),
+		SpanIDKey:     span.ID.String(),
+		"offset_ms":   float64(span.Timestamp.Sub(rootStart)) / float64(time.Millisecond),
+		"duration_ms": float64(span.Duration) / float64(time.Millisecond),
+	}
+	if span.ParentID != nil {
+		fields["parent_id"] = span.ParentID.String()
+	}
+	if span.LocalEndpoint != nil && span.LocalEndpoint.ServiceName != "" {
+		fields["service"] = span.LocalEndpoint.ServiceName
+	}
+	for key, value := range span.Tags {
+		fields["tag."+key] = value
+	}
+
+	l.logger.WithFields(fields).Info(strings.Repeat(spanIndent, depth) + span.Name)
+}
+
+// isLocalRoot checks if the span is the root of the work done in this process for a request
+func isLocalRoot(span model.SpanModel) bool {
+	return span.ParentID == nil || span.Kind == model.Server || span.Kind == model.Consumer
+}
+
+func sortByStart(spans []model.SpanModel) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Timestamp.Before(spans[j].Timestamp)
+	})
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogrusReporter(t *testing.T) {
+	t.Run("timing_tree", func(t *testing.T) {
+		// given
+		logger, hook := test.NewNullLogger()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "logged"}, NewLogrusReporter(logrus.NewEntry(logger))))
+
+		// when
+		root, ctx := StartSpanFromContext(context.Background(), "request")
+		child, childCtx := StartSpanFromContext(ctx, "load")
+		grandchild, _ := StartSpanFromContext(childCtx, "query")
+		grandchild.Finish()
+		child.Finish()
+		sibling, _ := StartSpanFromContext(ctx, "render")
+		sibling.Finish()
+
+		// then nothing is written until the root finishes
+		assert.Equal(t, 0, len(hook.AllEntries()))
+		root.Finish()
+
+		entries := hook.AllEntries()
+		require.Equal(t, 4, len(entries))
+		assert.Equal(t, "request", entries[0].Message)
+		assert.Equal(t, "  load", entries[1].Message)
+		assert.Equal(t, "    query", entries[2].Message)
+		assert.Equal(t, "  render", entries[3].Message)
+
+		traceID, spanID, _ := SpanIDsFromContext(ctx)
+		assert.Equal(t, traceID, entries[1].Data[TraceIDKey])
+		assert.Equal(t, spanID, entries[1].Data["parent_id"])
+		assert.NotContains(t, entries[0].Data, "parent_id")
+		assert.Equal(t, float64(0), entries[0].Data["offset_ms"])
+		assert.Equal(t, "logged", entries[0].Data["service"])
+		assert.Contains(t, entries[0].Data, "duration_ms")
+	})
+
+	t.Run("server_span_is_root", func(t *testing.T) {
+		// given
+		logger, hook := test.NewNullLogger()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "logged"}, NewLogrusReporter(logrus.NewEntry(logger))))
+		client, ctx := StartSpanFromContext(context.Background(), "client")
+		defer client.Finish()
+
+		// when
+		server, _ := StartSpanFromContext(ctx, "server", ext.SpanKindRPCServer)
+		server.SetTag("component", "test")
+		server.Finish()
+
+		// then
+		entries := hook.AllEntries()
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, "server", entries[0].Message)
+		assert.Equal(t, "test", entries[0].Data["tag.component"])
+	})
+
+	t.Run("close_flushes", func(t *testing.T) {
+		// given
+		logger, hook := test.NewNullLogger()
+		spanReporter := NewLogrusReporter(logrus.NewEntry(logger))
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "logged"}, spanReporter))
+		root, ctx := StartSpanFromContext(context.Background(), "request")
+		defer root.Finish()
+		child, _ := StartSpanFromContext(ctx, "orphan")
+		child.Finish()
+
+		// when
+		require.Nil(t, spanReporter.Close())
+
+		// then
+		entries := hook.AllEntries()
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, "orphan", entries[0].Message)
+	})
+
+	t.Run("from_config", func(t *testing.T) {
+		// when
+		spanReporter, err := NewReporter(TracingConfig{Reporter: ReporterLogrus})
+
+		// then
+		require.Nil(t, err)
+		require.IsType(t, &logrusReporter{}, spanReporter)
+		assert.IsType(t, &logrus.TextFormatter{}, spanReporter.(*logrusReporter).logger.Logger.Formatter)
+	})
+
+	t.Run("config_logger", func(t *testing.T) {
+		// given
+		logger, hook := test.NewNullLogger()
+		spanReporter, err := NewReporter(TracingConfig{Reporter: ReporterLogrus, Logger: logrus.NewEntry(logger)})
+		require.Nil(t, err)
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "logged"}, spanReporter))
+
+		// when
+		span, _ := StartSpanFromContext(context.Background(), "root")
+		span.Finish()
+
+		// then
+		require.Equal(t, 1, len(hook.AllEntries()))
+		assert.Equal(t, "root", hook.LastEntry().Message)
+	})
+}
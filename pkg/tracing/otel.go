@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	otelbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ReporterOTLPHTTP exports spans with OpenTelemetry over OTLP/HTTP
+	ReporterOTLPHTTP = "otlphttp"
+	// ReporterOTLPGRPC exports spans with OpenTelemetry over OTLP/gRPC
+	ReporterOTLPGRPC = "otlpgrpc"
+
+	otlpTracesPath          = "/v1/traces"
+	otelInstrumentationName = "github.com/jfbramlett/go-aop"
+)
+
+// otelSpanContext is implemented by the span contexts of the OpenTracing bridge
+type otelSpanContext interface {
+	IsValid() bool
+	TraceID() trace.TraceID
+	SpanID() trace.SpanID
+}
+
+// NewOTelTracer creates an OpenTelemetry tracer provider exporting to the OTLP collector at ReporterUrl (or the
+// exporter's default, which honors the OTEL_EXPORTER_OTLP_* environment variables). The caller should Shutdown
+// the provider on exit to flush any remaining spans
+func NewOTelTracer(ctx context.Context, cfg TracingConfig) (*sdktrace.TracerProvider, error) {
+	exporter, err := newOTLPExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := NewOTelTracerWithExporter(ctx, cfg, exporter)
+	if err != nil {
+		_ = exporter.Shutdown(ctx)
+	}
+	return provider, err
+}
+
+// NewOTelTracerWithExporter creates an OpenTelemetry tracer provider exporting to the given exporter. The
+// provider is set as the global OpenTelemetry tracer provider and an OpenTracing bridge to it is set as the
+// global OpenTracing tracer, so StartSpanFromContext and spans started with the OpenTelemetry API join the same
+// traces while users migrate
+func NewOTelTracerWithExporter(ctx context.Context, cfg TracingConfig, exporter sdktrace.SpanExporter) (*sdktrace.TracerProvider, error) {
+	if err := setPropagators(cfg); err != nil {
+		return nil, err
+	}
+	setBaggageKeys(cfg)
+
+	sampler, err := newOTelSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(cfg.Service)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	)
+
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	// the wrapper tracer puts spans started through OpenTracing in the context where OpenTelemetry can find them
+	bridgeTracer, _ := otelbridge.NewTracerPair(provider.Tracer(otelInstrumentationName))
+	bridgeTracer.SetTextMapPropagator(propagator)
+
+	otel.SetTracerProvider(otelbridge.NewTracerProvider(bridgeTracer, provider))
+	otel.SetTextMapPropagator(propagator)
+	opentracing.SetGlobalTracer(bridgeTracer)
+	// the batcher doesn't report its status
+	trackStatus(nil)
+
+	return provider, nil
+}
+
+func newOTLPExporter(ctx context.Context, cfg TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Reporter {
+	case ReporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		endpoint, err := reporterURL(cfg, otlpTracesPath)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ReporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		endpoint, err := reporterURL(cfg, "")
+		if err != nil {
+			return nil, err
+		}
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown OpenTelemetry reporter %q", cfg.Reporter)
+	}
+}
+
+// newOTelSampler creates the sampler selected by the config for root spans, child spans follow their parent
+func newOTelSampler(cfg TracingConfig) (sdktrace.Sampler, error) {
+	var root sdktrace.Sampler
+	switch cfg.Sampler {
+	case "", SamplerAlways:
+		root = sdktrace.AlwaysSample()
+	case SamplerNever:
+		root = sdktrace.NeverSample()
+	case SamplerProbabilistic:
+		if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+			return nil, fmt.Errorf("invalid rate for probabilistic sampler: %v", cfg.SampleRate)
+		}
+		root = sdktrace.TraceIDRatioBased(cfg.SampleRate)
+	case SamplerRateLimited:
+		limiter, err := newRateLimiter(cfg.SampleRate)
+		if err != nil {
+			return nil, err
+		}
+		root = &rateLimitedSampler{limiter: limiter}
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", cfg.Sampler)
+	}
+	return sdktrace.ParentBased(root), nil
+}
+
+type rateLimitedSampler struct {
+	limiter *rateLimiter
+}
+
+func (r *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if r.limiter.sample(0) {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (r *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimitedSampler{%v}", r.limiter.perSecond)
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestNewOTelTracer(t *testing.T) {
+	t.Run("opentracing_bridge", func(t *testing.T) {
+		// given
+		restoreGlobalTracing(t)
+		exporter := tracetest.NewInMemoryExporter()
+		provider, err := NewOTelTracerWithExporter(context.Background(), TracingConfig{Service: "otelService"}, exporter)
+		require.Nil(t, err)
+		defer provider.Shutdown(context.Background())
+
+		// when
+		span, ctx := StartSpanFromContext(context.Background(), "parent")
+		traceID, spanID, found := SpanIDsFromContext(ctx)
+		_, child := otel.Tracer("test").Start(ctx, "child")
+		child.End()
+		span.Finish()
+		require.Nil(t, provider.ForceFlush(context.Background()))
+
+		// then
+		spans := exporter.GetSpans()
+		require.Equal(t, 2, len(spans))
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, "parent", spans[1].Name)
+		assert.True(t, found)
+		assert.Equal(t, spans[1].SpanContext.TraceID().String(), traceID)
+		assert.Equal(t, spans[1].SpanContext.SpanID().String(), spanID)
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+		assert.Contains(t, spans[1].Resource.Attributes(), semconv.ServiceName("otelService"))
+	})
+
+	t.Run("tags_as_attributes", func(t *testing.T) {
+		// given
+		restoreGlobalTracing(t)
+		exporter := tracetest.NewInMemoryExporter()
+		provider, err := NewOTelTracerWithExporter(context.Background(), TracingConfig{Service: "otelService"}, exporter)
+		require.Nil(t, err)
+		defer provider.Shutdown(context.Background())
+
+		// when
+		span, _ := StartSpanFromContext(context.Background(), "tagged")
+		span.SetTag("component", "test")
+		span.Finish()
+		require.Nil(t, provider.ForceFlush(context.Background()))
+
+		// then
+		spans := exporter.GetSpans()
+		require.Equal(t, 1, len(spans))
+		assert.Contains(t, spans[0].Attributes, attribute.String("component", "test"))
+	})
+
+	t.Run("never_sample", func(t *testing.T) {
+		// given
+		restoreGlobalTracing(t)
+		exporter := tracetest.NewInMemoryExporter()
+		provider, err := NewOTelTracerWithExporter(context.Background(),
+			TracingConfig{Service: "otelService", Sampler: SamplerNever}, exporter)
+		require.Nil(t, err)
+		defer provider.Shutdown(context.Background())
+
+		// when
+		span, _ := StartSpanFromContext(context.Background(), "unsampled")
+		span.Finish()
+		require.Nil(t, provider.ForceFlush(context.Background()))
+
+		// then
+		assert.Equal(t, 0, len(exporter.GetSpans()))
+	})
+
+	t.Run("otlp_http", func(t *testing.T) {
+		// given
+		restoreGlobalTracing(t)
+		var requests int32
+		var path atomic.Value
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			path.Store(r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer collector.Close()
+
+		provider, err := NewOTelTracer(context.Background(),
+			TracingConfig{Service: "otelService", Reporter: ReporterOTLPHTTP, ReporterUrl: collector.URL})
+		require.Nil(t, err)
+
+		// when
+		span, _ := StartSpanFromContext(context.Background(), "exported")
+		span.Finish()
+		require.Nil(t, provider.Shutdown(context.Background()))
+
+		// then
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+		assert.Equal(t, otlpTracesPath, path.Load())
+	})
+
+	t.Run("otlp_grpc", func(t *testing.T) {
+		// given
+		restoreGlobalTracing(t)
+
+		// when
+		provider, err := NewOTelTracer(context.Background(),
+			TracingConfig{Service: "otelService", Reporter: ReporterOTLPGRPC, ReporterUrl: "http://localhost", ReporterPort: 4317})
+
+		// then
+		require.Nil(t, err)
+		require.NotNil(t, provider)
+		assert.Nil(t, provider.Shutdown(context.Background()))
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		// given
+		restoreGlobalTracing(t)
+
+		// when
+		_, badSampler := NewOTelTracer(context.Background(),
+			TracingConfig{Reporter: ReporterOTLPHTTP, Sampler: SamplerProbabilistic, SampleRate: 2})
+		_, badURL := NewOTelTracer(context.Background(), TracingConfig{Reporter: ReporterOTLPGRPC, ReporterUrl: "collector"})
+
+		// then
+		assert.NotNil(t, badSampler)
+		assert.NotNil(t, badURL)
+	})
+}
+
+// restoreGlobalTracing puts back the global tracers and propagators NewOTelTracer replaces once the test is done
+func restoreGlobalTracing(t *testing.T) {
+	tracer, tracerProvider, otelPropagator, httpPropagator :=
+		opentracing.GlobalTracer(), otel.GetTracerProvider(), otel.GetTextMapPropagator(), Propagator()
+	t.Cleanup(func() {
+		opentracing.SetGlobalTracer(tracer)
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(otelPropagator)
+		SetPropagator(httpPropagator)
+	})
+}
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIDHeader is the header carrying the legacy request id
+	RequestIDHeader = "xxx-request-id"
+
+	// PropagatorW3C propagates the W3C traceparent and tracestate headers
+	PropagatorW3C = "w3c"
+	// PropagatorB3 propagates the single b3 header
+	PropagatorB3 = "b3"
+	// PropagatorB3Multi propagates the X-B3-* headers
+	PropagatorB3Multi = "b3multi"
+	// PropagatorRequestID propagates the legacy request id header
+	PropagatorRequestID = "requestid"
+	// PropagatorBaggage propagates the W3C baggage header
+	PropagatorBaggage = "baggage"
+)
+
+// DefaultPropagators are used when none are configured, when extracting the later propagators take precedence
+var DefaultPropagators = []string{PropagatorB3Multi, PropagatorW3C, PropagatorRequestID, PropagatorBaggage}
+
+// nativePropagator reads and writes the headers the OpenTracing tracers themselves use (B3 for Zipkin and W3C
+// for the OpenTelemetry bridge), it translates between their span contexts and the configured propagators. Zipkin
+// is given the multi header form of B3 as it misreads 128 bit trace ids from the single header
+var nativePropagator = propagation.NewCompositeTextMapPropagator(
+	b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)), propagation.TraceContext{})
+
+var propagator atomic.Value
+
+func init() {
+	p, _ := NewPropagator(DefaultPropagators...)
+	SetPropagator(p)
+}
+
+// NewPropagator creates a propagator injecting all of the named formats and extracting from whichever are present
+func NewPropagator(names ...string) (propagation.TextMapPropagator, error) {
+	propagators := make([]propagation.TextMapPropagator, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case PropagatorW3C:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorRequestID:
+			propagators = append(propagators, requestIDPropagator{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		default:
+			return nil, fmt.Errorf("unknown propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+// SetPropagator sets the propagator used by InjectHTTP and ExtractHTTP
+func SetPropagator(p propagation.TextMapPropagator) {
+	propagator.Store(&p)
+}
+
+// Propagator gets the propagator used by InjectHTTP and ExtractHTTP
+func Propagator() propagation.TextMapPropagator {
+	return *propagator.Load().(*propagation.TextMapPropagator)
+}
+
+func setPropagators(cfg TracingConfig) error {
+	if len(cfg.Propagators) == 0 {
+		return nil
+	}
+	p, err := NewPropagator(cfg.Propagators...)
+	if err != nil {
+		return err
+	}
+	SetPropagator(p)
+	return nil
+}
+
+// InjectHTTP adds the span context of the span in the context (and the request id and baggage) to the outgoing
+// headers
+func InjectHTTP(ctx context.Context, header http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		native := http.Header{}
+		if err := span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(native)); err == nil {
+			ctx = nativePropagator.Extract(ctx, propagation.HeaderCarrier(native))
+		}
+	}
+	Propagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// ExtractHTTP reads the span context (and request id and baggage) from the incoming headers, the next span started
+// with StartSpanFromContext becomes a child of the remote span. Allow-listed baggage is added to the logger
+func ExtractHTTP(ctx context.Context, header http.Header) context.Context {
+	return logBaggage(Propagator().Extract(ctx, propagation.HeaderCarrier(header)))
+}
+
+// remoteParent gets the span context extracted from incoming headers in the form used by the global tracer, nil
+// if there isn't one or the tracer can't use it
+func remoteParent(ctx context.Context) opentracing.SpanContext {
+	remote := trace.SpanContextFromContext(ctx)
+	if !remote.IsValid() || !remote.IsRemote() {
+		return nil
+	}
+
+	native := http.Header{}
+	nativePropagator.Inject(trace.ContextWithRemoteSpanContext(context.Background(), remote), propagation.HeaderCarrier(native))
+	parent, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(native))
+	if err != nil {
+		return nil
+	}
+	return parent
+}
+
+// requestIDPropagator propagates the legacy request id
+type requestIDPropagator struct{}
+
+func (requestIDPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if requestID, found := RequestIDFromContext(ctx); found {
+		carrier.Set(RequestIDHeader, requestID)
+	}
+}
+
+func (requestIDPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if requestID := carrier.Get(RequestIDHeader); requestID != "" {
+		return SetTraceInContext(ctx, requestID)
+	}
+	return ctx
+}
+
+func (requestIDPropagator) Fields() []string {
+	return []string{RequestIDHeader}
+}
@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPropagation(t *testing.T) {
+	t.Run("zipkin_round_trip", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "propagated"}, spanReporter))
+
+		span, ctx := StartSpanFromContext(SetTraceInContext(context.Background(), "request-1"), "client")
+		header := http.Header{}
+
+		// when
+		InjectHTTP(ctx, header)
+		serverCtx := ExtractHTTP(context.Background(), header)
+		serverSpan, _ := StartSpanFromContext(serverCtx, "server")
+		serverSpan.Finish()
+		span.Finish()
+
+		// then
+		assert.NotEmpty(t, header.Get("traceparent"))
+		assert.NotEmpty(t, header.Get("X-B3-TraceId"))
+		assert.Equal(t, "request-1", header.Get(RequestIDHeader))
+		assert.Equal(t, "request-1", GetTraceFromContext(serverCtx))
+
+		spans := spanReporter.Flush()
+		require.Equal(t, 2, len(spans))
+		assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+		require.NotNil(t, spans[0].ParentID)
+		assert.Equal(t, spans[1].ID, *spans[0].ParentID)
+	})
+
+	t.Run("extract_w3c", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "propagated"}, spanReporter))
+		header := http.Header{}
+		header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		// when
+		span, ctx := StartSpanFromContext(ExtractHTTP(context.Background(), header), "server")
+		traceID, _, found := SpanIDsFromContext(ctx)
+		span.Finish()
+
+		// then
+		assert.True(t, found)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+		spans := spanReporter.Flush()
+		require.Equal(t, 1, len(spans))
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentID.String())
+	})
+
+	t.Run("otel_round_trip", func(t *testing.T) {
+		// given
+		exporter := tracetest.NewInMemoryExporter()
+		provider, err := NewOTelTracerWithExporter(context.Background(), TracingConfig{Service: "propagated"}, exporter)
+		require.Nil(t, err)
+		defer provider.Shutdown(context.Background())
+
+		span, ctx := StartSpanFromContext(context.Background(), "client")
+		header := http.Header{}
+
+		// when
+		InjectHTTP(ctx, header)
+		serverSpan, _ := StartSpanFromContext(ExtractHTTP(context.Background(), header), "server")
+		serverSpan.Finish()
+		span.Finish()
+		require.Nil(t, provider.ForceFlush(context.Background()))
+
+		// then
+		spans := exporter.GetSpans()
+		require.Equal(t, 2, len(spans))
+		assert.Equal(t, spans[1].SpanContext.TraceID(), spans[0].SpanContext.TraceID())
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	})
+
+	t.Run("configured_propagators", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "propagated", Propagators: []string{PropagatorB3}}, spanReporter))
+		defer SetPropagator(mustPropagator(t, DefaultPropagators...))
+
+		span, ctx := StartSpanFromContext(SetTraceInContext(context.Background(), "request-1"), "client")
+		defer span.Finish()
+		header := http.Header{}
+
+		// when
+		InjectHTTP(ctx, header)
+
+		// then
+		assert.NotEmpty(t, header.Get("b3"))
+		assert.Empty(t, header.Get("traceparent"))
+		assert.Empty(t, header.Get(RequestIDHeader))
+	})
+
+	t.Run("unknown_propagator", func(t *testing.T) {
+		// when
+		_, err := NewPropagator(PropagatorW3C, "smoke-signals")
+
+		// then
+		assert.NotNil(t, err)
+	})
+}
+
+func mustPropagator(t *testing.T, names ...string) propagation.TextMapPropagator {
+	p, err := NewPropagator(names...)
+	require.Nil(t, err)
+	return p
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/openzipkin/zipkin-go"
+)
+
+const (
+	// SamplerAlways records every trace
+	SamplerAlways = "always"
+	// SamplerNever records no traces
+	SamplerNever = "never"
+	// SamplerProbabilistic records SampleRate (between 0 and 1) of the traces
+	SamplerProbabilistic = "probabilistic"
+	// SamplerRateLimited records at most SampleRate traces a second
+	SamplerRateLimited = "ratelimited"
+)
+
+// NewSampler creates the sampler selected by the config
+func NewSampler(cfg TracingConfig) (zipkin.Sampler, error) {
+	switch cfg.Sampler {
+	case "", SamplerAlways:
+		return zipkin.AlwaysSample, nil
+	case SamplerNever:
+		return zipkin.NeverSample, nil
+	case SamplerProbabilistic:
+		// the boundary sampler decides on the trace id so every service makes the same decision for a trace
+		return zipkin.NewBoundarySampler(cfg.SampleRate, 0)
+	case SamplerRateLimited:
+		return NewRateLimitedSampler(cfg.SampleRate)
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", cfg.Sampler)
+	}
+}
+
+// NewRateLimitedSampler creates a sampler recording at most perSecond traces a second, allowing bursts of up to
+// a second's worth
+func NewRateLimitedSampler(perSecond float64) (zipkin.Sampler, error) {
+	limiter, err := newRateLimiter(perSecond)
+	if err != nil {
+		return nil, err
+	}
+	return limiter.sample, nil
+}
+
+func newRateLimiter(perSecond float64) (*rateLimiter, error) {
+	if perSecond <= 0 || math.IsInf(perSecond, 0) || math.IsNaN(perSecond) {
+		return nil, fmt.Errorf("invalid rate for rate limited sampler: %v", perSecond)
+	}
+
+	limiter := &rateLimiter{perSecond: perSecond, burst: math.Max(perSecond, 1), last: time.Now()}
+	limiter.balance = limiter.burst
+	return limiter, nil
+}
+
+type rateLimiter struct {
+	mux       sync.Mutex
+	perSecond float64
+	burst     float64
+	balance   float64
+	last      time.Time
+}
+
+func (r *rateLimiter) sample(_ uint64) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := time.Now()
+	r.balance = math.Min(r.burst, r.balance+now.Sub(r.last).Seconds()*r.perSecond)
+	r.last = now
+
+	if r.balance < 1 {
+		return false
+	}
+	r.balance--
+	return true
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSampler(t *testing.T) {
+	t.Run("always_and_never", func(t *testing.T) {
+		// when
+		always, err := NewSampler(TracingConfig{})
+		require.Nil(t, err)
+		never, err := NewSampler(TracingConfig{Sampler: SamplerNever})
+		require.Nil(t, err)
+
+		// then
+		assert.True(t, always(1))
+		assert.False(t, never(1))
+	})
+
+	t.Run("probabilistic", func(t *testing.T) {
+		// given
+		sampler, err := NewSampler(TracingConfig{Sampler: SamplerProbabilistic, SampleRate: 0.5})
+		require.Nil(t, err)
+
+		// when
+		sampled := 0
+		for id := uint64(0); id < 1000; id++ {
+			if sampler(id * 7919 * 104729) {
+				sampled++
+			}
+		}
+
+		// then
+		assert.InDelta(t, 500, sampled, 100)
+	})
+
+	t.Run("rate_limited", func(t *testing.T) {
+		// given
+		sampler, err := NewSampler(TracingConfig{Sampler: SamplerRateLimited, SampleRate: 2})
+		require.Nil(t, err)
+
+		// when
+		first, second, third := sampler(1), sampler(2), sampler(3)
+
+		// then
+		assert.True(t, first)
+		assert.True(t, second)
+		assert.False(t, third)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		// when
+		_, unknown := NewSampler(TracingConfig{Sampler: "sometimes"})
+		_, badRate := NewSampler(TracingConfig{Sampler: SamplerRateLimited})
+		_, badProbability := NewSampler(TracingConfig{Sampler: SamplerProbabilistic, SampleRate: 2})
+
+		// then
+		assert.NotNil(t, unknown)
+		assert.NotNil(t, badRate)
+		assert.NotNil(t, badProbability)
+	})
+}
@@ -0,0 +1,237 @@
+package tracing
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultMaxQueueSize is the number of spans waiting to be sent before further spans are dropped
+	DefaultMaxQueueSize = 1000
+
+	reporterTimeout = 5 * time.Second
+
+	spansSentMetric    = "tracing_spans_sent_total"
+	spansDroppedMetric = "tracing_spans_dropped_total"
+	spansQueuedMetric  = "tracing_spans_queued"
+)
+
+// ReporterStatus is a snapshot of the spans handled by a reporter
+type ReporterStatus struct {
+	// Sent is the number of spans accepted by the collector
+	Sent uint64
+	// Dropped is the number of spans lost, because the queue was full, the collector failed or the reporter was
+	// closed
+	Dropped uint64
+	// Queued is the number of spans waiting to be sent
+	Queued int64
+}
+
+// StatusReporter is a reporter that keeps track of the spans it has sent
+type StatusReporter interface {
+	reporter.Reporter
+	Status() ReporterStatus
+}
+
+// NewZipkinReporter creates a reporter sending spans to the Zipkin collector at the endpoint, at most maxQueueSize
+// spans are kept waiting to be sent (DefaultMaxQueueSize if 0 or less)
+func NewZipkinReporter(endpoint string, maxQueueSize int) StatusReporter {
+	return newZipkinReporter(endpoint, maxQueueSize, reporter.JSONSerializer{})
+}
+
+func newZipkinReporter(endpoint string, maxQueueSize int, serializer reporter.SpanSerializer) *zipkinReporter {
+	if maxQueueSize <= 0 {
+		maxQueueSize = DefaultMaxQueueSize
+	}
+
+	r := &zipkinReporter{maxQueueSize: int64(maxQueueSize)}
+	counting := &countingSerializer{SpanSerializer: serializer, reporter: r,
+		unserializable: make(map[*model.SpanModel]struct{})}
+	client := &statusClient{client: &http.Client{Timeout: reporterTimeout}, reporter: r, serializer: counting}
+	// the queue is limited here where the dropped spans can be counted
+	r.Reporter = zipkinhttp.NewReporter(endpoint,
+		zipkinhttp.Serializer(counting), zipkinhttp.Client(client), zipkinhttp.MaxBacklog(math.MaxInt32))
+	return r
+}
+
+type zipkinReporter struct {
+	reporter.Reporter
+
+	mux          sync.RWMutex
+	closed       bool
+	maxQueueSize int64
+	sent         uint64
+	dropped      uint64
+	queued       int64
+}
+
+func (r *zipkinReporter) Send(span model.SpanModel) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if r.closed {
+		atomic.AddUint64(&r.dropped, 1)
+		return
+	}
+	if atomic.AddInt64(&r.queued, 1) > r.maxQueueSize {
+		atomic.AddInt64(&r.queued, -1)
+		atomic.AddUint64(&r.dropped, 1)
+		return
+	}
+	r.Reporter.Send(span)
+}
+
+// Close sends the queued spans and stops the reporter, spans still queued if the collector can't be reached and
+// spans sent after this are dropped
+func (r *zipkinReporter) Close() error {
+	r.mux.Lock()
+	if r.closed {
+		r.mux.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mux.Unlock()
+
+	err := r.Reporter.Close()
+	if queued := atomic.SwapInt64(&r.queued, 0); queued > 0 {
+		atomic.AddUint64(&r.dropped, uint64(queued))
+	}
+	return err
+}
+
+func (r *zipkinReporter) Status() ReporterStatus {
+	return ReporterStatus{
+		Sent:    atomic.LoadUint64(&r.sent),
+		Dropped: atomic.LoadUint64(&r.dropped),
+		Queued:  atomic.LoadInt64(&r.queued),
+	}
+}
+
+func (r *zipkinReporter) batchDone(size int, ok bool) {
+	atomic.AddInt64(&r.queued, -int64(size))
+	if ok {
+		atomic.AddUint64(&r.sent, uint64(size))
+	} else {
+		atomic.AddUint64(&r.dropped, uint64(size))
+	}
+}
+
+// countingSerializer remembers the size of the batch being sent. The Zipkin reporter serializes and sends one
+// batch at a time from a single goroutine, keeping the batch to retry if either fails, so the fields are only used
+// from that goroutine
+type countingSerializer struct {
+	reporter.SpanSerializer
+	reporter *zipkinReporter
+
+	batchSize      int
+	unserializable map[*model.SpanModel]struct{}
+}
+
+func (c *countingSerializer) Serialize(spans []*model.SpanModel) ([]byte, error) {
+	body, err := c.SpanSerializer.Serialize(spans)
+	if err == nil {
+		c.batchSize = len(spans)
+		return body, nil
+	}
+
+	// a batch that can't be serialized would be retried forever, so the spans that can't be serialized are
+	// dropped (counting each once however often the batch is retried) and the others sent
+	valid := make([]*model.SpanModel, 0, len(spans))
+	for _, span := range spans {
+		if _, dropped := c.unserializable[span]; dropped {
+			continue
+		}
+		if _, err := c.SpanSerializer.Serialize([]*model.SpanModel{span}); err != nil {
+			c.unserializable[span] = struct{}{}
+			c.reporter.batchDone(1, false)
+			continue
+		}
+		valid = append(valid, span)
+	}
+	c.batchSize = len(valid)
+	return c.SpanSerializer.Serialize(valid)
+}
+
+// batchSent is called once the reporter has discarded the batch
+func (c *countingSerializer) batchSent() int {
+	size := c.batchSize
+	c.batchSize = 0
+	for span := range c.unserializable {
+		delete(c.unserializable, span)
+	}
+	return size
+}
+
+// statusClient counts the spans in each batch as sent or dropped depending on the collector's response, if the
+// request fails the reporter keeps the batch to retry so the spans stay queued
+type statusClient struct {
+	client     *http.Client
+	reporter   *zipkinReporter
+	serializer *countingSerializer
+}
+
+func (s *statusClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	s.reporter.batchDone(s.serializer.batchSent(), resp.StatusCode >= 200 && resp.StatusCode <= 299)
+	return resp, nil
+}
+
+// statusCollector exposes the status of the current reporter as prometheus metrics
+type statusCollector struct {
+	sent    *prometheus.Desc
+	dropped *prometheus.Desc
+	queued  *prometheus.Desc
+}
+
+// statusSource holds the reporter of the current tracer, nil if it doesn't keep a status
+type statusSource struct {
+	reporter StatusReporter
+}
+
+var currentStatus atomic.Value
+
+// NewReporterStatusCollector creates a prometheus collector for the status of the reporter of the tracer set up by
+// Init, it reports nothing if the reporter doesn't keep a status. It isn't registered by Init, register it with
+// the registry the service's metrics are served from
+func NewReporterStatusCollector() prometheus.Collector {
+	return &statusCollector{
+		sent:    prometheus.NewDesc(spansSentMetric, "Spans accepted by the tracing collector", nil, nil),
+		dropped: prometheus.NewDesc(spansDroppedMetric, "Spans lost before reaching the tracing collector", nil, nil),
+		queued:  prometheus.NewDesc(spansQueuedMetric, "Spans waiting to be sent to the tracing collector", nil, nil),
+	}
+}
+
+func (c *statusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sent
+	ch <- c.dropped
+	ch <- c.queued
+}
+
+func (c *statusCollector) Collect(ch chan<- prometheus.Metric) {
+	source, _ := currentStatus.Load().(statusSource)
+	if source.reporter == nil {
+		return
+	}
+
+	status := source.reporter.Status()
+	ch <- prometheus.MustNewConstMetric(c.sent, prometheus.CounterValue, float64(status.Sent))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(status.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(status.Queued))
+}
+
+// trackStatus makes the reporter the one reported on by the status collector
+func trackStatus(spanReporter reporter.Reporter) {
+	statusReporter, _ := spanReporter.(StatusReporter)
+	currentStatus.Store(statusSource{reporter: statusReporter})
+}
@@ -0,0 +1,207 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("shutdown_flushes", func(t *testing.T) {
+		// given
+		collector, spans := newTestCollector(http.StatusAccepted)
+		defer collector.Close()
+
+		shutdown, err := Init(TracingConfig{Service: "flushed", ReporterUrl: collector.URL})
+		require.Nil(t, err)
+		spanReporter := currentStatus.Load().(statusSource).reporter
+		require.NotNil(t, spanReporter)
+
+		span, _ := StartSpanFromContext(context.Background(), "pending")
+		span.Finish()
+
+		// when
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = shutdown(ctx)
+
+		// then
+		require.Nil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(spans))
+		assert.Equal(t, ReporterStatus{Sent: 1}, spanReporter.Status())
+		assert.Equal(t, opentracing.NoopTracer{}, opentracing.GlobalTracer())
+		assert.Nil(t, shutdown(ctx))
+	})
+
+	t.Run("shutdown_deadline", func(t *testing.T) {
+		// given
+		release := make(chan struct{})
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer collector.Close()
+		defer close(release)
+
+		shutdown, err := Init(TracingConfig{Service: "stuck", ReporterUrl: collector.URL})
+		require.Nil(t, err)
+
+		span, _ := StartSpanFromContext(context.Background(), "pending")
+		span.Finish()
+
+		// when
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err = shutdown(ctx)
+
+		// then
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+func TestZipkinReporterStatus(t *testing.T) {
+	t.Run("queue_full", func(t *testing.T) {
+		// given
+		collector, spans := newTestCollector(http.StatusAccepted)
+		defer collector.Close()
+
+		shutdown, err := Init(TracingConfig{Service: "queued", ReporterUrl: collector.URL, MaxQueueSize: 1})
+		require.Nil(t, err)
+		spanReporter := currentStatus.Load().(statusSource).reporter
+
+		// when
+		for i := 0; i < 3; i++ {
+			span, _ := StartSpanFromContext(context.Background(), "queued")
+			span.Finish()
+		}
+		queued := spanReporter.Status()
+		require.Nil(t, shutdown(context.Background()))
+
+		// then
+		assert.Equal(t, ReporterStatus{Dropped: 2, Queued: 1}, queued)
+		assert.Equal(t, ReporterStatus{Sent: 1, Dropped: 2}, spanReporter.Status())
+		assert.Equal(t, int32(1), atomic.LoadInt32(spans))
+	})
+
+	t.Run("collector_failure", func(t *testing.T) {
+		// given
+		collector, _ := newTestCollector(http.StatusInternalServerError)
+		defer collector.Close()
+
+		shutdown, err := Init(TracingConfig{Service: "failed", ReporterUrl: collector.URL})
+		require.Nil(t, err)
+		spanReporter := currentStatus.Load().(statusSource).reporter
+
+		span, _ := StartSpanFromContext(context.Background(), "failed")
+		span.Finish()
+
+		// when
+		require.Nil(t, shutdown(context.Background()))
+
+		// then
+		assert.Equal(t, ReporterStatus{Dropped: 1}, spanReporter.Status())
+	})
+
+	t.Run("serialization_failure", func(t *testing.T) {
+		// given
+		collector, spans := newTestCollector(http.StatusAccepted)
+		defer collector.Close()
+
+		spanReporter := newZipkinReporter(collector.URL+"/api/v2/spans", 0, failingSerializer{})
+		spanReporter.Send(model.SpanModel{SpanContext: model.SpanContext{TraceID: model.TraceID{Low: 1}, ID: 1}, Name: "sent"})
+		spanReporter.Send(model.SpanModel{SpanContext: model.SpanContext{TraceID: model.TraceID{Low: 1}, ID: 2}, Name: "unserializable"})
+
+		// when
+		require.Nil(t, spanReporter.Close())
+
+		// then
+		assert.Equal(t, ReporterStatus{Sent: 1, Dropped: 1}, spanReporter.Status())
+		assert.Equal(t, int32(1), atomic.LoadInt32(spans))
+	})
+
+	t.Run("collector_unreachable", func(t *testing.T) {
+		// given
+		collector, _ := newTestCollector(http.StatusAccepted)
+		collector.Close()
+
+		spanReporter := NewZipkinReporter(collector.URL+"/api/v2/spans", 0)
+		spanReporter.Send(model.SpanModel{SpanContext: model.SpanContext{TraceID: model.TraceID{Low: 1}, ID: 1}, Name: "lost"})
+
+		// when
+		err := spanReporter.Close()
+
+		// then
+		assert.NotNil(t, err)
+		assert.Equal(t, ReporterStatus{Dropped: 1}, spanReporter.Status())
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		// given
+		collector, _ := newTestCollector(http.StatusAccepted)
+		defer collector.Close()
+
+		shutdown, err := Init(TracingConfig{Service: "measured", ReporterUrl: collector.URL})
+		require.Nil(t, err)
+
+		span, _ := StartSpanFromContext(context.Background(), "measured")
+		span.Finish()
+		require.Nil(t, shutdown(context.Background()))
+
+		registry := prometheus.NewRegistry()
+		require.Nil(t, registry.Register(NewReporterStatusCollector()))
+
+		// when
+		families, err := registry.Gather()
+
+		// then
+		require.Nil(t, err)
+		values := map[string]float64{}
+		for _, family := range families {
+			metric := family.Metric[0]
+			if metric.Counter != nil {
+				values[family.GetName()] = metric.Counter.GetValue()
+			} else {
+				values[family.GetName()] = metric.Gauge.GetValue()
+			}
+		}
+		assert.Equal(t, map[string]float64{spansSentMetric: 1, spansDroppedMetric: 0, spansQueuedMetric: 0}, values)
+	})
+}
+
+// failingSerializer fails to serialize any batch with a span named "unserializable"
+type failingSerializer struct {
+	reporter.JSONSerializer
+}
+
+func (f failingSerializer) Serialize(spans []*model.SpanModel) ([]byte, error) {
+	for _, span := range spans {
+		if span.Name == "unserializable" {
+			return nil, errors.New("unserializable span")
+		}
+	}
+	return f.JSONSerializer.Serialize(spans)
+}
+
+// newTestCollector creates a Zipkin collector responding with the given status, counting the spans it receives
+func newTestCollector(status int) (*httptest.Server, *int32) {
+	var spans int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// each span in the JSON array has a traceId
+		atomic.AddInt32(&spans, int32(strings.Count(string(body), `"traceId"`)))
+		w.WriteHeader(status)
+	}))
+	return collector, &spans
+}
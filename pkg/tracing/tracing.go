@@ -3,50 +3,242 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
 
 	"github.com/opentracing/opentracing-go"
 
 	zipkinot "github.com/openzipkin-contrib/zipkin-go-opentracing"
 	"github.com/openzipkin/zipkin-go"
-	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	"github.com/openzipkin/zipkin-go/reporter"
+	zipkinlog "github.com/openzipkin/zipkin-go/reporter/log"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/sirupsen/logrus"
 )
 
 const EndpointURL = "http://localhost:9411/api/v2/spans"
-const ctxRequestId = "requestId"
 
-func InitTracing(cfg TracingConfig) {
-	err := NewTracer(cfg)
+const (
+	// RequestIDKey is the span tag and log field holding the request id
+	RequestIDKey = "request_id"
+	// TraceIDKey is the log field holding the trace id of the active span
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the log field holding the span id of the active span
+	SpanIDKey = "span_id"
+
+	unknownTrace = "unknown"
+)
+
+type requestIDCtxKey struct{}
+
+var ctxRequestId = requestIDCtxKey{}
+
+const (
+	// ReporterZipkin sends spans to the Zipkin collector at ReporterUrl
+	ReporterZipkin = "zipkin"
+	// ReporterLog writes spans to stdout
+	ReporterLog = "log"
+	// ReporterMemory records spans in memory, intended for tests
+	ReporterMemory = "memory"
+	// ReporterNoop discards spans
+	ReporterNoop = "noop"
+
+	zipkinSpansPath = "/api/v2/spans"
+)
+
+// ShutdownFunc flushes any pending spans and stops the tracer, it gives up and returns the context's error if
+// the spans can't be flushed before the context is done
+type ShutdownFunc func(ctx context.Context) error
+
+// InitTracing creates a tracer from the config and sets it as the global tracer, use Init to be able to flush
+// spans on exit
+func InitTracing(cfg TracingConfig) error {
+	return NewTracer(cfg)
+}
+
+// Init creates a tracer from the config and sets it as the global tracer. The returned func should be called on
+// exit to flush pending spans, after which the global tracer no longer records spans
+func Init(cfg TracingConfig) (ShutdownFunc, error) {
+	if cfg.Reporter == ReporterOTLPHTTP || cfg.Reporter == ReporterOTLPGRPC {
+		provider, err := NewOTelTracer(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		tracer := opentracing.GlobalTracer()
+		return newShutdownFunc(tracer, provider.Shutdown), nil
+	}
+
+	spanReporter, err := NewReporter(cfg)
 	if err != nil {
-		return
+		return nil, err
 	}
+
+	tracer, err := newZipkinTracer(cfg, spanReporter)
+	if err != nil {
+		_ = spanReporter.Close()
+		return nil, err
+	}
+	opentracing.SetGlobalTracer(tracer)
+	trackStatus(spanReporter)
+
+	return newShutdownFunc(tracer, func(context.Context) error { return spanReporter.Close() }), nil
 }
 
+// NewTracer creates a tracer reporting to the reporter selected by the config and sets it as the global tracer,
+// use Init to be able to flush spans on exit
 func NewTracer(cfg TracingConfig) error {
+	_, err := Init(cfg)
+	return err
+}
+
+// NewTracerWithReporter creates a tracer reporting to the given reporter and sets it as the global tracer
+func NewTracerWithReporter(cfg TracingConfig, spanReporter reporter.Reporter) error {
+	tracer, err := newZipkinTracer(cfg, spanReporter)
+	if err != nil {
+		return err
+	}
+
+	opentracing.SetGlobalTracer(tracer)
+	trackStatus(spanReporter)
+	return nil
+}
 
-	reporter := zipkinhttp.NewReporter("http://zipkinhost:9411/api/v2/spans")
+func newZipkinTracer(cfg TracingConfig, spanReporter reporter.Reporter) (opentracing.Tracer, error) {
+	if err := setPropagators(cfg); err != nil {
+		return nil, err
+	}
+	setBaggageKeys(cfg)
+
+	sampler, err := NewSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// create our local service endpoint
-	endpoint, err := zipkin.NewEndpoint("myService", "myservice.mydomain.com:80")
+	endpoint, err := zipkin.NewEndpoint(cfg.Service, cfg.HostPort)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// initialize our tracer
-	nativeTracer, err := zipkin.NewTracer(reporter, zipkin.WithLocalEndpoint(endpoint))
+	nativeTracer, err := zipkin.NewTracer(spanReporter, zipkin.WithLocalEndpoint(endpoint), zipkin.WithSampler(sampler))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// use zipkin-go-opentracing to wrap our tracer
-	tracer := zipkinot.Wrap(nativeTracer)
+	return zipkinot.Wrap(nativeTracer), nil
+}
 
-	// optionally set as Global OpenTracing tracer instance
-	opentracing.SetGlobalTracer(tracer)
+// newShutdownFunc creates the func that stops the tracer, the global tracer is replaced with a noop tracer (if
+// it is still this tracer) so no spans are started while the pending ones are flushed
+func newShutdownFunc(tracer opentracing.Tracer, flush func(ctx context.Context) error) ShutdownFunc {
+	var once sync.Once
+	var err error
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			once.Do(func() {
+				if opentracing.GlobalTracer() == tracer {
+					opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+				}
+				err = flush(ctx)
+			})
+			close(done)
+		}()
 
-	return err
+		select {
+		case <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// NewReporter creates the reporter selected by the config, a memory reporter is a *recorder.ReporterRecorder and a
+// Zipkin reporter is a StatusReporter
+func NewReporter(cfg TracingConfig) (reporter.Reporter, error) {
+	switch cfg.Reporter {
+	case "", ReporterZipkin:
+		endpoint, err := ReporterEndpoint(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewZipkinReporter(endpoint, cfg.MaxQueueSize), nil
+	case ReporterLog:
+		return zipkinlog.NewReporter(log.New(os.Stdout, "", log.LstdFlags)), nil
+	case ReporterLogrus:
+		if cfg.Logger != nil {
+			return NewLogrusReporter(cfg.Logger), nil
+		}
+		// the service's logger is typically JSON which would escape the indentation of the span tree
+		logger := logrus.New()
+		logger.Out = os.Stdout
+		logger.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+		return NewLogrusReporter(logrus.NewEntry(logger)), nil
+	case ReporterMemory:
+		return recorder.NewReporter(), nil
+	case ReporterNoop:
+		return reporter.NewNoopReporter(), nil
+	default:
+		return nil, fmt.Errorf("unknown reporter %q", cfg.Reporter)
+	}
+}
+
+// ReporterEndpoint gets the URL spans are sent to, built from the reporter URL and port in the config. The port
+// is only used if the URL doesn't have one and the Zipkin spans path is used if the URL doesn't have a path
+func ReporterEndpoint(cfg TracingConfig) (string, error) {
+	endpoint, err := reporterURL(cfg, zipkinSpansPath)
+	if err != nil || endpoint != "" {
+		return endpoint, err
+	}
+	return EndpointURL, nil
+}
+
+// reporterURL builds the URL of the collector from the reporter URL and port in the config, empty if neither are
+// set so the reporter's default can be used
+func reporterURL(cfg TracingConfig, defaultPath string) (string, error) {
+	if cfg.ReporterUrl == "" {
+		if cfg.ReporterPort == 0 {
+			return "", nil
+		}
+		cfg.ReporterUrl = "http://localhost"
+	}
+
+	endpoint, err := url.Parse(cfg.ReporterUrl)
+	if err != nil {
+		return "", err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return "", fmt.Errorf("invalid reporter URL %q", cfg.ReporterUrl)
+	}
+
+	if endpoint.Port() == "" && cfg.ReporterPort != 0 {
+		endpoint.Host = net.JoinHostPort(endpoint.Hostname(), strconv.Itoa(int(cfg.ReporterPort)))
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = defaultPath
+	}
+
+	return endpoint.String(), nil
 }
 
+// StartSpanFromContext starts a span that is a child of the span in the context, or of the remote span extracted
+// by ExtractHTTP if there is no span in the context yet. The span is tagged with the request id if there is one
 func StartSpanFromContext(ctx context.Context, name string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	if SpanFromContext(ctx) == nil {
+		if parent := remoteParent(ctx); parent != nil {
+			opts = append(opts, opentracing.ChildOf(parent))
+		}
+	}
+	if requestID, found := RequestIDFromContext(ctx); found {
+		opts = append(opts, opentracing.Tag{Key: RequestIDKey, Value: requestID})
+	}
 	return opentracing.StartSpanFromContext(ctx, name, opts...)
 }
 
@@ -54,14 +246,71 @@ func SpanFromContext(ctx context.Context) opentracing.Span {
 	return opentracing.SpanFromContext(ctx)
 }
 
+// TraceIDFromContext gets the id of the trace the span in the context belongs to, found is false if there is no
+// span or its tracer doesn't expose ids
+func TraceIDFromContext(ctx context.Context) (traceID string, found bool) {
+	traceID, _, found = SpanIDsFromContext(ctx)
+	return traceID, found
+}
+
+// SpanIDsFromContext gets the trace and span ids of the span in the context, found is false if there is no span
+// or its tracer doesn't expose ids
+func SpanIDsFromContext(ctx context.Context) (traceID string, spanID string, found bool) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return "", "", false
+	}
+
+	switch sc := span.Context().(type) {
+	case zipkinot.SpanContext:
+		return sc.TraceID.String(), sc.ID.String(), true
+	case otelSpanContext:
+		// spans from the OpenTelemetry bridge
+		if sc.IsValid() {
+			return sc.TraceID().String(), sc.SpanID().String(), true
+		}
+	}
+
+	return "", "", false
+}
+
+// GetTraceFromContext gets the id used to correlate the request, this is the trace id of the span in the context
+// if there is one, otherwise the request id or "unknown" if there is neither
 func GetTraceFromContext(ctx context.Context) string {
-	traceId := ctx.Value(ctxRequestId)
-	if traceId != nil {
-		return fmt.Sprintf("%v", traceId)
+	if traceId, found := TraceIDFromContext(ctx); found {
+		return traceId
+	}
+	if requestId, found := RequestIDFromContext(ctx); found {
+		return requestId
+	}
+	return unknownTrace
+}
+
+// SetTraceInContext adds the request id to the context, the span in the context (and any started from it) is
+// tagged with the request id
+func SetTraceInContext(ctx context.Context, requestId string) context.Context {
+	if span := SpanFromContext(ctx); span != nil {
+		span.SetTag(RequestIDKey, requestId)
 	}
-	return "unknown"
+	return context.WithValue(ctx, ctxRequestId, requestId)
 }
 
-func SetTraceInContext(ctx context.Context, traceId string) context.Context {
-	return context.WithValue(ctx, ctxRequestId, traceId)
+// RequestIDFromContext gets the request id added to the context with SetTraceInContext
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestId, ok := ctx.Value(ctxRequestId).(string)
+	return requestId, ok
+}
+
+// LogFields gets the fields used to correlate log entries with the request, the trace and span ids of the span in
+// the context and the request id (each only if present)
+func LogFields(ctx context.Context) logrus.Fields {
+	fields := logrus.Fields{}
+	if traceId, spanId, found := SpanIDsFromContext(ctx); found {
+		fields[TraceIDKey] = traceId
+		fields[SpanIDKey] = spanId
+	}
+	if requestId, found := RequestIDFromContext(ctx); found {
+		fields[RequestIDKey] = requestId
+	}
+	return fields
 }
@@ -0,0 +1,208 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanIDsFromContext(t *testing.T) {
+	t.Run("span_in_context", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "spanIds"}, spanReporter))
+		span, ctx := StartSpanFromContext(context.Background(), "test")
+		span.Finish()
+
+		// when
+		traceID, spanID, found := SpanIDsFromContext(ctx)
+
+		// then
+		assert.True(t, found)
+		spans := spanReporter.Flush()
+		require.Equal(t, 1, len(spans))
+		assert.Equal(t, spans[0].TraceID.String(), traceID)
+		assert.Equal(t, spans[0].ID.String(), spanID)
+	})
+
+	t.Run("unknown_tracer", func(t *testing.T) {
+		// given
+		opentracing.SetGlobalTracer(&mocktracer.MockTracer{})
+		_, ctx := StartSpanFromContext(context.Background(), "test")
+
+		// when
+		traceID, found := TraceIDFromContext(ctx)
+
+		// then
+		assert.False(t, found)
+		assert.Equal(t, "", traceID)
+	})
+
+	t.Run("no_span", func(t *testing.T) {
+		// when
+		traceID, found := TraceIDFromContext(context.Background())
+
+		// then
+		assert.False(t, found)
+		assert.Equal(t, "", traceID)
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	t.Run("no_span", func(t *testing.T) {
+		// given
+		ctx := SetTraceInContext(context.Background(), "request-1")
+
+		// when
+		requestID, found := RequestIDFromContext(ctx)
+
+		// then
+		assert.True(t, found)
+		assert.Equal(t, "request-1", requestID)
+		assert.Equal(t, "request-1", GetTraceFromContext(ctx))
+		assert.Equal(t, "unknown", GetTraceFromContext(context.Background()))
+		assert.Nil(t, ctx.Value("requestId"))
+	})
+
+	t.Run("span_in_context", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "requestId"}, spanReporter))
+		span, ctx := StartSpanFromContext(context.Background(), "test")
+
+		// when
+		ctx = SetTraceInContext(ctx, "request-1")
+		span.Finish()
+
+		// then
+		spans := spanReporter.Flush()
+		require.Equal(t, 1, len(spans))
+		assert.Equal(t, spans[0].TraceID.String(), GetTraceFromContext(ctx))
+		assert.Equal(t, "request-1", spans[0].Tags[RequestIDKey])
+	})
+
+	t.Run("started_span_tagged", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "requestId"}, spanReporter))
+		ctx := SetTraceInContext(context.Background(), "request-1")
+
+		// when
+		span, _ := StartSpanFromContext(ctx, "test")
+		span.Finish()
+
+		// then
+		spans := spanReporter.Flush()
+		require.Equal(t, 1, len(spans))
+		assert.Equal(t, "request-1", spans[0].Tags[RequestIDKey])
+	})
+
+	t.Run("log_fields", func(t *testing.T) {
+		// given
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(TracingConfig{Service: "requestId"}, spanReporter))
+		span, ctx := StartSpanFromContext(SetTraceInContext(context.Background(), "request-1"), "test")
+		span.Finish()
+
+		// when
+		fields := LogFields(ctx)
+
+		// then
+		spans := spanReporter.Flush()
+		require.Equal(t, 1, len(spans))
+		assert.Equal(t, spans[0].TraceID.String(), fields[TraceIDKey])
+		assert.Equal(t, spans[0].ID.String(), fields[SpanIDKey])
+		assert.Equal(t, "request-1", fields[RequestIDKey])
+		assert.Empty(t, LogFields(context.Background()))
+	})
+}
+
+func TestNewTracer(t *testing.T) {
+	t.Run("memory_reporter", func(t *testing.T) {
+		// given
+		cfg := TracingConfig{Service: "tracedService", Reporter: ReporterMemory}
+		spanReporter, err := NewReporter(cfg)
+		require.Nil(t, err)
+		require.Nil(t, NewTracerWithReporter(cfg, spanReporter))
+
+		// when
+		span, _ := StartSpanFromContext(context.Background(), "test")
+		span.Finish()
+
+		// then
+		spans := spanReporter.(*recorder.ReporterRecorder).Flush()
+		require.Equal(t, 1, len(spans))
+		assert.Equal(t, "test", spans[0].Name)
+		assert.Equal(t, "tracedService", spans[0].LocalEndpoint.ServiceName)
+	})
+
+	t.Run("never_sample", func(t *testing.T) {
+		// given
+		cfg := TracingConfig{Service: "tracedService", Sampler: SamplerNever}
+		spanReporter := recorder.NewReporter()
+		require.Nil(t, NewTracerWithReporter(cfg, spanReporter))
+
+		// when
+		span, _ := StartSpanFromContext(context.Background(), "test")
+		span.Finish()
+
+		// then
+		assert.Equal(t, 0, len(spanReporter.Flush()))
+	})
+
+	t.Run("reporters", func(t *testing.T) {
+		for _, name := range []string{"", ReporterZipkin, ReporterLog, ReporterMemory, ReporterNoop} {
+			// when
+			spanReporter, err := NewReporter(TracingConfig{Reporter: name})
+
+			// then
+			require.Nil(t, err, name)
+			assert.Nil(t, spanReporter.Close())
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		// when
+		unknownReporter := NewTracer(TracingConfig{Reporter: "carrier-pigeon"})
+		unknownSampler := NewTracer(TracingConfig{Reporter: ReporterNoop, Sampler: "sometimes"})
+		invalidURL := InitTracing(TracingConfig{ReporterUrl: "zipkin"})
+
+		// then
+		assert.NotNil(t, unknownReporter)
+		assert.NotNil(t, unknownSampler)
+		assert.NotNil(t, invalidURL)
+	})
+}
+
+func TestReporterEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      TracingConfig
+		expected string
+	}{
+		{name: "default", cfg: TracingConfig{}, expected: EndpointURL},
+		{name: "port_only", cfg: TracingConfig{ReporterPort: 9412}, expected: "http://localhost:9412/api/v2/spans"},
+		{name: "url_and_port", cfg: TracingConfig{ReporterUrl: "http://zipkin", ReporterPort: 9411},
+			expected: "http://zipkin:9411/api/v2/spans"},
+		{name: "url_with_port", cfg: TracingConfig{ReporterUrl: "https://zipkin:443", ReporterPort: 9411},
+			expected: "https://zipkin:443/api/v2/spans"},
+		{name: "url_with_path", cfg: TracingConfig{ReporterUrl: "http://collector/zipkin/spans"},
+			expected: "http://collector/zipkin/spans"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			endpoint, err := ReporterEndpoint(test.cfg)
+
+			// then
+			require.Nil(t, err)
+			assert.Equal(t, test.expected, endpoint)
+		})
+	}
+}
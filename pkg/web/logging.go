@@ -1,7 +1,6 @@
 package web
 
 import (
-	"github.com/sirupsen/logrus"
 	"net/http"
 
 	"github.com/jfbramlett/go-aop/pkg/tracing"
@@ -12,7 +11,6 @@ import (
 const (
 	endpoint      = "endpoint"
 	requestMethod = "requestMethod"
-	traceId       = "traceId"
 )
 
 type LoggingMiddleware struct {
@@ -22,13 +20,12 @@ type LoggingMiddleware struct {
 func (l *LoggingMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		requestId := tracing.GetTraceFromContext(r.Context())
-		logger, reqCtx := logging.UpdateInContext(r.Context(), logrus.Fields{
-			"name": l.method,
-			endpoint: r.RequestURI,
-			requestMethod: r.Method,
-			traceId: requestId,
-		})
+		// the request is correlated by the trace_id, span_id and request_id fields
+		fields := tracing.LogFields(r.Context())
+		fields["name"] = l.method
+		fields[endpoint] = r.RequestURI
+		fields[requestMethod] = r.Method
+		logger, reqCtx := logging.UpdateInContext(r.Context(), fields)
 		logger.Info("request received")
 		next.ServeHTTP(w, r.WithContext(reqCtx))
 
@@ -2,6 +2,7 @@ package web
 
 import (
 	"github.com/jfbramlett/go-aop/pkg/tracing"
+	"github.com/opentracing/opentracing-go/ext"
 	"net/http"
 )
 
@@ -13,7 +14,9 @@ type SpanMiddleware struct {
 func (s *SpanMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		sp, spanCtx := tracing.StartSpanFromContext(r.Context(), r.RequestURI)
+		// continue the trace of the caller (if there is one)
+		reqCtx := tracing.ExtractHTTP(r.Context(), r.Header)
+		sp, spanCtx := tracing.StartSpanFromContext(reqCtx, r.RequestURI, ext.SpanKindRPCServer)
 
 		next.ServeHTTP(w, r.WithContext(spanCtx))
 
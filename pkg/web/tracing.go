@@ -9,7 +9,7 @@ import (
 )
 
 const (
-	HeaderRequestId = "xxx-request-id"
+	HeaderRequestId = tracing.RequestIDHeader
 )
 
 type TracingMiddleware struct {